@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+// certificateStore holds the certificates supplied by the providers for a TLS
+// entrypoint, indexed by server name. The TLS config of the entrypoint looks
+// them up on each handshake, so that a reload replaces them without
+// restarting the listener.
+type certificateStore struct {
+	nameToCertificate *safe.Safe
+}
+
+func newCertificateStore() *certificateStore {
+	return &certificateStore{nameToCertificate: safe.New(map[string]*tls.Certificate{})}
+}
+
+// add adds a certificate for its server names, unless a previous certificate
+// is already valid for them. It must be called before the store is used by a
+// TLS config.
+func (store *certificateStore) add(certificate *tls.Certificate) {
+	nameToCertificate := store.nameToCertificate.Get().(map[string]*tls.Certificate)
+	for _, name := range certificateNames(certificate.Leaf) {
+		if _, exists := nameToCertificate[name]; !exists {
+			nameToCertificate[name] = certificate
+		}
+	}
+}
+
+// update replaces the certificates of the store by the ones of other.
+func (store *certificateStore) update(other *certificateStore) {
+	store.nameToCertificate.Set(other.nameToCertificate.Get())
+}
+
+// getCertificate returns the certificate valid for a server name, directly or
+// through a wildcard, or nil if there is none.
+func (store *certificateStore) getCertificate(serverName string) *tls.Certificate {
+	nameToCertificate := store.nameToCertificate.Get().(map[string]*tls.Certificate)
+	name := strings.TrimSuffix(strings.ToLower(serverName), ".")
+	if certificate, ok := nameToCertificate[name]; ok {
+		return certificate
+	}
+	labels := strings.Split(name, ".")
+	if len(labels) > 1 {
+		labels[0] = "*"
+		if certificate, ok := nameToCertificate[strings.Join(labels, ".")]; ok {
+			return certificate
+		}
+	}
+	return nil
+}
+
+// loadTLSCertificate loads a certificate supplied by a provider, parsing its
+// leaf to find its server names.
+func loadTLSCertificate(tlsCertificate *types.TLSCertificate) (*tls.Certificate, error) {
+	certificates := Certificates{{CertFile: tlsCertificate.CertFile, KeyFile: tlsCertificate.KeyFile}}
+	config, err := certificates.CreateTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	certificate := &config.Certificates[0]
+	certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(certificateNames(certificate.Leaf)) == 0 {
+		return nil, errors.New("No server name in certificate")
+	}
+	return certificate, nil
+}
+
+// certificateNames returns the lowercased common name and DNS alternative
+// names of a certificate.
+func certificateNames(leaf *x509.Certificate) []string {
+	var names []string
+	if len(leaf.Subject.CommonName) > 0 {
+		names = append(names, strings.ToLower(leaf.Subject.CommonName))
+	}
+	for _, name := range leaf.DNSNames {
+		names = append(names, strings.ToLower(name))
+	}
+	return names
+}
+
+// providesTLSCertificates returns whether a provider able to supply TLS
+// certificates is enabled, the TLS entrypoints then serving them.
+func providesTLSCertificates(globalConfiguration GlobalConfiguration) bool {
+	return globalConfiguration.File != nil ||
+		globalConfiguration.Web != nil && !globalConfiguration.Web.ReadOnly ||
+		globalConfiguration.Consul != nil ||
+		globalConfiguration.Etcd != nil ||
+		globalConfiguration.Zookeeper != nil ||
+		globalConfiguration.Boltdb != nil ||
+		globalConfiguration.Kubernetes != nil
+}
+
+// redactConfigurations returns the configurations without the keys of their
+// TLS certificates, to be exposed by the API.
+func redactConfigurations(configurations configs) configs {
+	redacted := make(configs, len(configurations))
+	for providerName, configuration := range configurations {
+		redacted[providerName] = redactConfiguration(configuration)
+	}
+	return redacted
+}
+
+// redactConfiguration returns a copy of a configuration without the keys of
+// its TLS certificates, or the configuration itself if it has none.
+func redactConfiguration(configuration *types.Configuration) *types.Configuration {
+	if configuration == nil || len(configuration.TLSCertificates) == 0 {
+		return configuration
+	}
+	redacted := *configuration
+	redacted.TLSCertificates = make([]*types.TLSCertificate, len(configuration.TLSCertificates))
+	for i, tlsCertificate := range configuration.TLSCertificates {
+		redacted.TLSCertificates[i] = &types.TLSCertificate{
+			EntryPoints: tlsCertificate.EntryPoints,
+			CertFile:    tlsCertificate.CertFile,
+		}
+	}
+	return &redacted
+}
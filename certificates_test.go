@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/types"
+)
+
+// generateCertificate returns a self-signed certificate and its key in PEM.
+func generateCertificate(t *testing.T, commonName string, dnsNames ...string) *types.TLSCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error marshalling key: %v", err)
+	}
+	return &types.TLSCertificate{
+		CertFile: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		KeyFile:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})),
+	}
+}
+
+func loadCertificate(t *testing.T, tlsCertificate *types.TLSCertificate) *tls.Certificate {
+	certificate, err := loadTLSCertificate(tlsCertificate)
+	if err != nil {
+		t.Fatalf("Error loading certificate: %v", err)
+	}
+	return certificate
+}
+
+func TestCertificateStore(t *testing.T) {
+	foo := loadCertificate(t, generateCertificate(t, "foo.example.com", "bar.example.com"))
+	wildcard := loadCertificate(t, generateCertificate(t, "*.example.com"))
+	other := loadCertificate(t, generateCertificate(t, "", "foo.example.com"))
+
+	store := newCertificateStore()
+	store.add(foo)
+	store.add(wildcard)
+	store.add(other)
+
+	cases := []struct {
+		serverName string
+		expected   *tls.Certificate
+	}{
+		{"foo.example.com", foo},
+		{"BAR.example.com.", foo},
+		{"baz.example.com", wildcard},
+		{"example.com", nil},
+		{"foo.example.org", nil},
+		{"", nil},
+	}
+	for _, c := range cases {
+		if actual := store.getCertificate(c.serverName); actual != c.expected {
+			t.Fatalf("Unexpected certificate for %q", c.serverName)
+		}
+	}
+
+	if _, err := loadTLSCertificate(generateCertificate(t, "")); err == nil {
+		t.Fatalf("Certificate without server name should return an error")
+	}
+	if _, err := loadTLSCertificate(&types.TLSCertificate{CertFile: "foo", KeyFile: "bar"}); err == nil {
+		t.Fatalf("Invalid certificate should return an error")
+	}
+}
+
+func TestLoadTLSCertificates(t *testing.T) {
+	server := &Server{}
+	serverEntryPoints := map[string]*serverEntryPoint{
+		"http":  {},
+		"https": {certificates: newCertificateStore()},
+		"tls":   {certificates: newCertificateStore()},
+	}
+	all := generateCertificate(t, "all.example.com")
+	https := generateCertificate(t, "https.example.com")
+	https.EntryPoints = []string{"https"}
+	undefined := generateCertificate(t, "http.example.com")
+	undefined.EntryPoints = []string{"https", "http"}
+
+	server.loadTLSCertificates(configs{
+		"file": &types.Configuration{
+			TLSCertificates: []*types.TLSCertificate{all, https, undefined, {CertFile: "foo", KeyFile: "bar"}},
+		},
+	}, serverEntryPoints)
+
+	cases := []struct {
+		entryPointName string
+		serverName     string
+		expected       bool
+	}{
+		{"https", "all.example.com", true},
+		{"tls", "all.example.com", true},
+		{"https", "https.example.com", true},
+		{"tls", "https.example.com", false},
+		{"https", "http.example.com", false},
+	}
+	for _, c := range cases {
+		if actual := serverEntryPoints[c.entryPointName].certificates.getCertificate(c.serverName) != nil; actual != c.expected {
+			t.Fatalf("Expected certificate %s on entrypoint %s to be %t, got %t", c.serverName, c.entryPointName, c.expected, actual)
+		}
+	}
+}
+
+func TestCreateTLSConfigProviderCertificates(t *testing.T) {
+	store := newCertificateStore()
+	server := &Server{serverEntryPoints: map[string]*serverEntryPoint{"https": {certificates: store}}}
+	config, err := server.createTLSConfig("https", &TLS{}, nil)
+	if err != nil {
+		t.Fatalf("Error creating TLS config: %v", err)
+	}
+	clientHello := &tls.ClientHelloInfo{ServerName: "foo.example.com"}
+	if _, err := config.GetCertificate(clientHello); err == nil {
+		t.Fatalf("Server name without certificate should return an error")
+	}
+
+	// a reload replaces the certificates of the existing TLS config
+	reloaded := newCertificateStore()
+	certificate := loadCertificate(t, generateCertificate(t, "foo.example.com"))
+	reloaded.add(certificate)
+	store.update(reloaded)
+	if actual, err := config.GetCertificate(clientHello); err != nil || actual != certificate {
+		t.Fatalf("Expected reloaded certificate, got %v", err)
+	}
+
+	server.serverEntryPoints["https"].certificates = nil
+	if _, err := server.createTLSConfig("https", &TLS{}, nil); err == nil {
+		t.Fatalf("TLS entrypoint without certificates should return an error")
+	}
+}
+
+func TestBuildEntryPointsCertificateStore(t *testing.T) {
+	globalConfiguration := GlobalConfiguration{EntryPoints: EntryPoints{"https": {TLS: &TLS{}}}}
+	server := &Server{}
+	if serverEntryPoints := server.buildEntryPoints(globalConfiguration); serverEntryPoints["https"].certificates != nil {
+		t.Fatalf("TLS entrypoint without provider supplying certificates should have no certificate store")
+	}
+
+	globalConfiguration.Web = &WebProvider{ReadOnly: true}
+	if serverEntryPoints := server.buildEntryPoints(globalConfiguration); serverEntryPoints["https"].certificates != nil {
+		t.Fatalf("TLS entrypoint with a read only web provider should have no certificate store")
+	}
+
+	globalConfiguration.File = &provider.File{}
+	if serverEntryPoints := server.buildEntryPoints(globalConfiguration); serverEntryPoints["https"].certificates == nil {
+		t.Fatalf("TLS entrypoint with a file provider should have a certificate store")
+	}
+}
+
+func TestRedactConfiguration(t *testing.T) {
+	tlsCertificate := generateCertificate(t, "foo.example.com")
+	tlsCertificate.EntryPoints = []string{"https"}
+	configuration := &types.Configuration{TLSCertificates: []*types.TLSCertificate{tlsCertificate}}
+
+	redacted := redactConfigurations(configs{"web": configuration})["web"]
+	if len(redacted.TLSCertificates) != 1 || len(redacted.TLSCertificates[0].KeyFile) > 0 {
+		t.Fatalf("Expected a certificate without key, got %+v", redacted.TLSCertificates)
+	}
+	if redacted.TLSCertificates[0].CertFile != tlsCertificate.CertFile || !reflect.DeepEqual(redacted.TLSCertificates[0].EntryPoints, tlsCertificate.EntryPoints) {
+		t.Fatalf("Expected the certificate and entrypoints to be kept, got %+v", redacted.TLSCertificates[0])
+	}
+	if len(tlsCertificate.KeyFile) == 0 {
+		t.Fatalf("The key of the original configuration should be kept")
+	}
+}
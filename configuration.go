@@ -9,6 +9,7 @@ import (
 	"github.com/containous/traefik/types"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -27,13 +28,14 @@ type GlobalConfiguration struct {
 	AccessLogsFile            string                  `description:"Access logs file"`
 	TraefikLogsFile           string                  `description:"Traefik logs file"`
 	LogLevel                  string                  `short:"l" description:"Log level"`
-	EntryPoints               EntryPoints             `description:"Entrypoints definition using format: --entryPoints='Name:http Address::8000 Redirect.EntryPoint:https' --entryPoints='Name:https Address::4442 TLS:tests/traefik.crt,tests/traefik.key'"`
+	EntryPoints               EntryPoints             `description:"Entrypoints definition using format: --entryPoints='Name:http Address::8000 Redirect.EntryPoint:https' --entryPoints='Name:https Address::4442 TLS:tests/traefik.crt,tests/traefik.key' --entryPoints='Name:admin Address::8081 WhitelistSourceRange:10.0.0.0/8,192.168.1.0/24 Compress:true'"`
 	Constraints               types.Constraints       `description:"Filter services by constraint, matching with service tags."`
 	ACME                      *acme.ACME              `description:"Enable ACME (Let's Encrypt): automatic SSL"`
 	DefaultEntryPoints        DefaultEntryPoints      `description:"Entrypoints to be used by frontends that do not specify any entrypoint"`
 	ProvidersThrottleDuration time.Duration           `description:"Backends throttle duration: minimum duration between 2 events from providers before applying a new configuration. It avoids unnecessary reloads if multiples events are sent in a short amount of time."`
 	MaxIdleConnsPerHost       int                     `description:"If non-zero, controls the maximum idle (keep-alive) to keep per-host.  If zero, DefaultMaxIdleConnsPerHost is used"`
 	Retry                     *Retry                  `description:"Enable retry sending request if network error"`
+	NotFound                  *types.ErrorPage        `description:"Page served by a backend for requests matching no frontend"`
 	Docker                    *provider.Docker        `description:"Enable Docker backend"`
 	File                      *provider.File          `description:"Enable File backend"`
 	Web                       *WebProvider            `description:"Enable Web backend"`
@@ -96,7 +98,7 @@ func (ep *EntryPoints) String() string {
 // Set's argument is a string to be parsed to set the flag.
 // It's a comma-separated list, so we split it.
 func (ep *EntryPoints) Set(value string) error {
-	regex := regexp.MustCompile("(?:Name:(?P<Name>\\S*))\\s*(?:Address:(?P<Address>\\S*))?\\s*(?:TLS:(?P<TLS>\\S*))?\\s*((?P<TLSACME>TLS))?\\s*(?:CA:(?P<CA>\\S*))?\\s*(?:Redirect.EntryPoint:(?P<RedirectEntryPoint>\\S*))?\\s*(?:Redirect.Regex:(?P<RedirectRegex>\\S*))?\\s*(?:Redirect.Replacement:(?P<RedirectReplacement>\\S*))?")
+	regex := regexp.MustCompile("(?:Name:(?P<Name>\\S*))\\s*(?:Address:(?P<Address>\\S*))?\\s*(?:TLS:(?P<TLS>\\S*))?\\s*((?P<TLSACME>TLS)(?:\\s|$))?\\s*(?:CA:(?P<CA>\\S*))?\\s*(?:TLS.MinVersion:(?P<TLSMinVersion>\\S*))?\\s*(?:TLS.CipherSuites:(?P<TLSCipherSuites>\\S*))?\\s*(?:TLS.CurvePreferences:(?P<TLSCurvePreferences>\\S*))?\\s*(?:TLS.PreferServerCipherSuites:(?P<TLSPreferServerCipherSuites>\\S*))?\\s*(?:Redirect.EntryPoint:(?P<RedirectEntryPoint>\\S*))?\\s*(?:Redirect.Regex:(?P<RedirectRegex>\\S*))?\\s*(?:Redirect.Replacement:(?P<RedirectReplacement>\\S*))?\\s*(?:WhitelistSourceRange:(?P<WhitelistSourceRange>\\S*))?\\s*(?:WhitelistXForwardedForDepth:(?P<WhitelistXForwardedForDepth>\\S*))?\\s*(?:Compress:(?P<Compress>\\S*))?\\s*(?:Protocol:(?P<Protocol>\\S*))?\\s*(?:ProxyProtocol.TrustedIPs:(?P<ProxyProtocolTrustedIPs>\\S*))?\\s*(?:ForwardedHeaders.TrustedIPs:(?P<ForwardedHeadersTrustedIPs>\\S*))?\\s*(?:ForwardedHeaders.Forwarded:(?P<ForwardedHeadersForwarded>\\S*))?")
 	match := regex.FindAllStringSubmatch(value, -1)
 	if match == nil {
 		return errors.New("Bad EntryPoints format: " + value)
@@ -126,6 +128,28 @@ func (ep *EntryPoints) Set(value string) error {
 		files := strings.Split(result["CA"], ",")
 		tls.ClientCAFiles = files
 	}
+	if len(result["TLSMinVersion"]) > 0 || len(result["TLSCipherSuites"]) > 0 || len(result["TLSCurvePreferences"]) > 0 || len(result["TLSPreferServerCipherSuites"]) > 0 {
+		if tls == nil {
+			return errors.New("TLS settings without TLS: " + value)
+		}
+		tls.MinVersion = result["TLSMinVersion"]
+		if len(result["TLSCipherSuites"]) > 0 {
+			tls.CipherSuites = strings.Split(result["TLSCipherSuites"], ",")
+		}
+		if len(result["TLSCurvePreferences"]) > 0 {
+			tls.CurvePreferences = strings.Split(result["TLSCurvePreferences"], ",")
+		}
+		if len(result["TLSPreferServerCipherSuites"]) > 0 {
+			var err error
+			tls.PreferServerCipherSuites, err = strconv.ParseBool(result["TLSPreferServerCipherSuites"])
+			if err != nil {
+				return fmt.Errorf("Bad TLS.PreferServerCipherSuites format: %s", result["TLSPreferServerCipherSuites"])
+			}
+		}
+		if err := tls.checkPolicy(); err != nil {
+			return err
+		}
+	}
 	var redirect *Redirect
 	if len(result["RedirectEntryPoint"]) > 0 || len(result["RedirectRegex"]) > 0 || len(result["RedirectReplacement"]) > 0 {
 		redirect = &Redirect{
@@ -135,10 +159,60 @@ func (ep *EntryPoints) Set(value string) error {
 		}
 	}
 
+	var whitelistSourceRange []string
+	if len(result["WhitelistSourceRange"]) > 0 {
+		whitelistSourceRange = strings.Split(result["WhitelistSourceRange"], ",")
+	}
+	var whitelistXForwardedForDepth int
+	if len(result["WhitelistXForwardedForDepth"]) > 0 {
+		depth, err := strconv.Atoi(result["WhitelistXForwardedForDepth"])
+		if err != nil {
+			return fmt.Errorf("Bad WhitelistXForwardedForDepth format: %s", result["WhitelistXForwardedForDepth"])
+		}
+		whitelistXForwardedForDepth = depth
+	}
+	var compress bool
+	if len(result["Compress"]) > 0 {
+		var err error
+		compress, err = strconv.ParseBool(result["Compress"])
+		if err != nil {
+			return fmt.Errorf("Bad Compress format: %s", result["Compress"])
+		}
+	}
+	if len(result["Protocol"]) > 0 && result["Protocol"] != "http" && result["Protocol"] != "tcp" && result["Protocol"] != "udp" {
+		return fmt.Errorf("Bad Protocol format: %s", result["Protocol"])
+	}
+	var proxyProtocol *ProxyProtocol
+	if len(result["ProxyProtocolTrustedIPs"]) > 0 {
+		proxyProtocol = &ProxyProtocol{
+			TrustedIPs: strings.Split(result["ProxyProtocolTrustedIPs"], ","),
+		}
+	}
+	var forwardedHeaders *ForwardedHeaders
+	if len(result["ForwardedHeadersTrustedIPs"]) > 0 || len(result["ForwardedHeadersForwarded"]) > 0 {
+		forwardedHeaders = &ForwardedHeaders{}
+		if len(result["ForwardedHeadersTrustedIPs"]) > 0 {
+			forwardedHeaders.TrustedIPs = strings.Split(result["ForwardedHeadersTrustedIPs"], ",")
+		}
+		if len(result["ForwardedHeadersForwarded"]) > 0 {
+			var err error
+			forwardedHeaders.Forwarded, err = strconv.ParseBool(result["ForwardedHeadersForwarded"])
+			if err != nil {
+				return fmt.Errorf("Bad ForwardedHeaders.Forwarded format: %s", result["ForwardedHeadersForwarded"])
+			}
+		}
+	}
+
 	(*ep)[result["Name"]] = &EntryPoint{
-		Address:  result["Address"],
-		TLS:      tls,
-		Redirect: redirect,
+		Address:                     result["Address"],
+		TLS:                         tls,
+		Redirect:                    redirect,
+		WhitelistSourceRange:        whitelistSourceRange,
+		WhitelistXForwardedForDepth: whitelistXForwardedForDepth,
+		Compress:                    compress,
+		Protocol:                    result["Protocol"],
+		ProxyProtocol:               proxyProtocol,
+		ForwardedHeaders:            forwardedHeaders,
 	}
 
 	return nil
@@ -159,10 +233,30 @@ func (ep *EntryPoints) Type() string {
 
 // EntryPoint holds an entry point configuration of the reverse proxy (ip, port, TLS...)
 type EntryPoint struct {
-	Network  string
-	Address  string
-	TLS      *TLS
-	Redirect *Redirect
+	Network                     string
+	Address                     string
+	TLS                         *TLS
+	Redirect                    *Redirect
+	WhitelistSourceRange        []string
+	WhitelistXForwardedForDepth int
+	Compress                    bool
+	Protocol                    string
+	ProxyProtocol               *ProxyProtocol
+	ForwardedHeaders            *ForwardedHeaders
+}
+
+// ProxyProtocol enables the PROXY protocol on an entry point, for the
+// connections from trusted IPs
+type ProxyProtocol struct {
+	TrustedIPs []string
+}
+
+// ForwardedHeaders configures the forwarded headers of an entry point: the
+// X-Forwarded-* and Forwarded headers are only kept from the trusted IPs, and
+// the Forwarded header is added if Forwarded is true
+type ForwardedHeaders struct {
+	TrustedIPs []string
+	Forwarded  bool
 }
 
 // Redirect configures a redirection of an entry point to another, or to an URL
@@ -173,9 +267,82 @@ type Redirect struct {
 }
 
 // TLS configures TLS for an entry point
+// MinVersion, CipherSuites and CurvePreferences are the names of the Go
+// constants, like VersionTLS12, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 and
+// CurveP256
 type TLS struct {
-	Certificates  Certificates
-	ClientCAFiles []string
+	Certificates             Certificates
+	ClientCAFiles            []string
+	MinVersion               string
+	CipherSuites             []string
+	CurvePreferences         []string
+	PreferServerCipherSuites bool
+}
+
+var tlsVersions = map[string]uint16{
+	"VersionSSL30": tls.VersionSSL30,
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS12": tls.VersionTLS12,
+}
+
+var tlsCipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+}
+
+var tlsCurves = map[string]tls.CurveID{
+	"CurveP256": tls.CurveP256,
+	"CurveP384": tls.CurveP384,
+	"CurveP521": tls.CurveP521,
+}
+
+// checkPolicy returns an error if a version, cipher suite or curve name is
+// unknown
+func (t *TLS) checkPolicy() error {
+	return t.applyPolicy(&tls.Config{})
+}
+
+// applyPolicy sets the minimum version, cipher suites and curves of config
+func (t *TLS) applyPolicy(config *tls.Config) error {
+	if len(t.MinVersion) > 0 {
+		version, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return errors.New("Unknown TLS version " + t.MinVersion)
+		}
+		config.MinVersion = version
+	}
+	for _, name := range t.CipherSuites {
+		cipherSuite, ok := tlsCipherSuites[strings.TrimSpace(name)]
+		if !ok {
+			return errors.New("Unknown TLS cipher suite " + name)
+		}
+		config.CipherSuites = append(config.CipherSuites, cipherSuite)
+	}
+	for _, name := range t.CurvePreferences {
+		curve, ok := tlsCurves[strings.TrimSpace(name)]
+		if !ok {
+			return errors.New("Unknown TLS curve " + name)
+		}
+		config.CurvePreferences = append(config.CurvePreferences, curve)
+	}
+	config.PreferServerCipherSuites = t.PreferServerCipherSuites
+	return nil
 }
 
 // Certificates defines traefik certificates type
@@ -257,7 +424,8 @@ type Certificate struct {
 
 // Retry contains request retry config
 type Retry struct {
-	Attempts int `description:"Number of attempts"`
+	Attempts            int   `description:"Number of attempts"`
+	MaxRequestBodyBytes int64 `description:"Maximum size of the request bodies buffered to be replayed on retry"`
 }
 
 // NewTraefikDefaultPointersConfiguration creates a TraefikConfiguration with pointers default values
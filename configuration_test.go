@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/tls"
+	"reflect"
+	"testing"
+)
+
+func TestEntryPointsSetTLSPolicy(t *testing.T) {
+	entryPoints := EntryPoints{}
+	err := entryPoints.Set("Name:https Address::443 TLS:cert.pem,key.pem TLS.MinVersion:VersionTLS12 TLS.CipherSuites:TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 TLS.CurvePreferences:CurveP256 TLS.PreferServerCipherSuites:true")
+	if err != nil {
+		t.Fatalf("Error parsing entrypoint: %v", err)
+	}
+	expected := &TLS{
+		Certificates:             Certificates{{CertFile: "cert.pem", KeyFile: "key.pem"}},
+		MinVersion:               "VersionTLS12",
+		CipherSuites:             []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+		CurvePreferences:         []string{"CurveP256"},
+		PreferServerCipherSuites: true,
+	}
+	if !reflect.DeepEqual(entryPoints["https"].TLS, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, entryPoints["https"].TLS)
+	}
+
+	// TLS without certificate, for ACME
+	if err := entryPoints.Set("Name:acme Address::443 TLS TLS.MinVersion:VersionTLS12"); err != nil {
+		t.Fatalf("Error parsing entrypoint: %v", err)
+	}
+	if entryPoints["acme"].TLS == nil || entryPoints["acme"].TLS.MinVersion != "VersionTLS12" {
+		t.Fatalf("Unexpected TLS %+v", entryPoints["acme"].TLS)
+	}
+
+	invalids := []string{
+		"Name:https Address::443 TLS:cert.pem,key.pem TLS.MinVersion:TLS12",
+		"Name:https Address::443 TLS:cert.pem,key.pem TLS.CipherSuites:TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,foo",
+		"Name:https Address::443 TLS:cert.pem,key.pem TLS.CurvePreferences:P256",
+		"Name:https Address::443 TLS:cert.pem,key.pem TLS.PreferServerCipherSuites:foo",
+		"Name:http Address::80 TLS.MinVersion:VersionTLS12",
+	}
+	for _, invalid := range invalids {
+		if err := (&EntryPoints{}).Set(invalid); err == nil {
+			t.Fatalf("Expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestTLSApplyPolicy(t *testing.T) {
+	config := &tls.Config{}
+	err := (&TLS{
+		MinVersion:       "VersionTLS11",
+		CipherSuites:     []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+		CurvePreferences: []string{"CurveP384", "CurveP521"},
+	}).applyPolicy(config)
+	if err != nil {
+		t.Fatalf("Error applying TLS policy: %v", err)
+	}
+	if config.MinVersion != tls.VersionTLS11 || !reflect.DeepEqual(config.CipherSuites, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}) || !reflect.DeepEqual(config.CurvePreferences, []tls.CurveID{tls.CurveP384, tls.CurveP521}) || config.PreferServerCipherSuites {
+		t.Fatalf("Unexpected TLS config %+v", config)
+	}
+}
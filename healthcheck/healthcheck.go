@@ -0,0 +1,178 @@
+// Package healthcheck actively checks backend servers and removes the failing
+// ones from their load-balancer until they recover.
+package healthcheck
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/safe"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+// LoadBalancer includes the load-balancer operations used by the health checks.
+type LoadBalancer interface {
+	RemoveServer(u *url.URL) error
+	UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error
+	Servers() []*url.URL
+}
+
+// Options holds the health check configuration of a backend.
+type Options struct {
+	Path           string
+	Interval       time.Duration
+	Timeout        time.Duration
+	ExpectedStatus int
+	// Weights holds the configured weight of each server, indexed by URL,
+	// used when putting a recovered server back into the load-balancer.
+	Weights map[string]int
+	LB      LoadBalancer
+	// Transport is the round tripper of the backend, the default transport
+	// being used if nil.
+	Transport http.RoundTripper
+}
+
+// BackendHealthCheck holds the health check state of a backend.
+type BackendHealthCheck struct {
+	Options
+	lock         sync.Mutex
+	disabledURLs []*url.URL
+	client       *http.Client
+}
+
+// NewBackendHealthCheck returns a new BackendHealthCheck.
+func NewBackendHealthCheck(options Options) *BackendHealthCheck {
+	return &BackendHealthCheck{
+		Options: options,
+		client:  &http.Client{Timeout: options.Timeout, Transport: options.Transport},
+	}
+}
+
+// HealthCheck runs the health checks of every backend.
+type HealthCheck struct {
+	stop chan bool
+	lock sync.Mutex
+}
+
+// New returns a new HealthCheck.
+func New() *HealthCheck {
+	return &HealthCheck{}
+}
+
+// SetBackendsConfiguration stops the running health checks and starts
+// checking the given backends.
+func (hc *HealthCheck) SetBackendsConfiguration(backends map[string]*BackendHealthCheck) {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+	if hc.stop != nil {
+		close(hc.stop)
+	}
+	hc.stop = make(chan bool)
+	for backendName, backend := range backends {
+		currentBackendName := backendName
+		currentBackend := backend
+		stop := hc.stop
+		safe.Go(func() {
+			hc.execute(stop, currentBackendName, currentBackend)
+		})
+	}
+}
+
+// Stop stops all running health checks.
+func (hc *HealthCheck) Stop() {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+	if hc.stop != nil {
+		close(hc.stop)
+		hc.stop = nil
+	}
+}
+
+func (hc *HealthCheck) execute(stop chan bool, backendName string, backend *BackendHealthCheck) {
+	log.Debugf("Starting health check for backend %s every %s", backendName, backend.Interval)
+	// check right away so that a reload does not put back dead servers for a whole interval
+	backend.checkServers(backendName)
+	ticker := time.NewTicker(backend.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			log.Debugf("Stopping health check for backend %s", backendName)
+			return
+		case <-ticker.C:
+			backend.checkServers(backendName)
+		}
+	}
+}
+
+func (backend *BackendHealthCheck) checkServers(backendName string) {
+	enabledURLs := backend.LB.Servers()
+	backend.lock.Lock()
+	disabledURLs := backend.disabledURLs
+	backend.lock.Unlock()
+	var newDisabledURLs []*url.URL
+	for _, disabledURL := range disabledURLs {
+		if backend.checkServer(disabledURL) {
+			log.Infof("Health check up: putting back server %s in backend %s", disabledURL, backendName)
+			if err := backend.LB.UpsertServer(disabledURL, roundrobin.Weight(backend.weight(disabledURL))); err != nil {
+				log.Errorf("Error putting back server %s in backend %s: %v", disabledURL, backendName, err)
+				newDisabledURLs = append(newDisabledURLs, disabledURL)
+			}
+		} else {
+			newDisabledURLs = append(newDisabledURLs, disabledURL)
+		}
+	}
+
+	for _, enabledURL := range enabledURLs {
+		if !backend.checkServer(enabledURL) {
+			log.Warnf("Health check down: removing server %s from backend %s", enabledURL, backendName)
+			if err := backend.LB.RemoveServer(enabledURL); err != nil {
+				log.Errorf("Error removing server %s from backend %s: %v", enabledURL, backendName, err)
+				continue
+			}
+			newDisabledURLs = append(newDisabledURLs, enabledURL)
+		}
+	}
+	backend.lock.Lock()
+	backend.disabledURLs = newDisabledURLs
+	backend.lock.Unlock()
+}
+
+// Disabled returns whether a server is removed from the load-balancer by the
+// health check, until it recovers.
+func (backend *BackendHealthCheck) Disabled(serverURL *url.URL) bool {
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+	for _, disabledURL := range backend.disabledURLs {
+		if disabledURL.String() == serverURL.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (backend *BackendHealthCheck) weight(serverURL *url.URL) int {
+	if weight, ok := backend.Weights[serverURL.String()]; ok {
+		return weight
+	}
+	return 1
+}
+
+func (backend *BackendHealthCheck) checkServer(serverURL *url.URL) bool {
+	checkURL := *serverURL
+	checkURL.Path = strings.TrimSuffix(serverURL.Path, "/") + backend.Path
+	resp, err := backend.client.Get(checkURL.String())
+	if err != nil {
+		log.Debugf("Health check failed for %s: %v", checkURL.String(), err)
+		return false
+	}
+	resp.Body.Close()
+	if backend.ExpectedStatus != 0 {
+		return resp.StatusCode == backend.ExpectedStatus
+	}
+	return resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusBadRequest
+}
@@ -0,0 +1,150 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+type testLoadBalancer struct {
+	lock    sync.Mutex
+	servers []*url.URL
+}
+
+func (lb *testLoadBalancer) RemoveServer(u *url.URL) error {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	for i, server := range lb.servers {
+		if server.String() == u.String() {
+			lb.servers = append(lb.servers[:i], lb.servers[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (lb *testLoadBalancer) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	lb.servers = append(lb.servers, u)
+	return nil
+}
+
+func (lb *testLoadBalancer) Servers() []*url.URL {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	return append([]*url.URL{}, lb.servers...)
+}
+
+type toggleHandler struct {
+	lock    sync.Mutex
+	healthy bool
+	paths   []string
+}
+
+func (h *toggleHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.paths = append(h.paths, r.URL.Path)
+	if h.healthy {
+		rw.WriteHeader(http.StatusOK)
+	} else {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+func (h *toggleHandler) setHealthy(healthy bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.healthy = healthy
+}
+
+func TestCheckServers(t *testing.T) {
+	healthyHandler := &toggleHandler{healthy: true}
+	healthyServer := httptest.NewServer(healthyHandler)
+	defer healthyServer.Close()
+	failingHandler := &toggleHandler{healthy: false}
+	failingServer := httptest.NewServer(failingHandler)
+	defer failingServer.Close()
+
+	healthyURL, _ := url.Parse(healthyServer.URL)
+	failingURL, _ := url.Parse(failingServer.URL)
+	lb := &testLoadBalancer{servers: []*url.URL{healthyURL, failingURL}}
+	backend := NewBackendHealthCheck(Options{
+		Path:     "/health",
+		Interval: time.Second,
+		Timeout:  time.Second,
+		LB:       lb,
+	})
+
+	backend.checkServers("backend1")
+	if len(lb.Servers()) != 1 || lb.Servers()[0] != healthyURL {
+		t.Fatalf("expected only %s in load-balancer, got %v", healthyURL, lb.Servers())
+	}
+	if len(backend.disabledURLs) != 1 {
+		t.Fatalf("expected 1 disabled server, got %d", len(backend.disabledURLs))
+	}
+	if !backend.Disabled(failingURL) || backend.Disabled(healthyURL) {
+		t.Fatalf("expected only %s to be disabled", failingURL)
+	}
+	if healthyHandler.paths[0] != "/health" {
+		t.Fatalf("expected health check on /health, got %s", healthyHandler.paths[0])
+	}
+
+	failingHandler.setHealthy(true)
+	backend.checkServers("backend1")
+	if len(lb.Servers()) != 2 {
+		t.Fatalf("expected 2 servers in load-balancer, got %v", lb.Servers())
+	}
+	if len(backend.disabledURLs) != 0 {
+		t.Fatalf("expected no disabled server, got %d", len(backend.disabledURLs))
+	}
+}
+
+func TestCheckServerExpectedStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	serverURL, _ := url.Parse(ts.URL)
+
+	cases := []struct {
+		expectedStatus int
+		healthy        bool
+	}{
+		{0, true},
+		{http.StatusNoContent, true},
+		{http.StatusOK, false},
+	}
+	for _, c := range cases {
+		backend := NewBackendHealthCheck(Options{
+			Path:           "/",
+			Timeout:        time.Second,
+			ExpectedStatus: c.expectedStatus,
+		})
+		if healthy := backend.checkServer(serverURL); healthy != c.healthy {
+			t.Errorf("expected status %d: got healthy %v, want %v", c.expectedStatus, healthy, c.healthy)
+		}
+	}
+}
+
+func TestCheckServerTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+	serverURL, _ := url.Parse(ts.URL)
+
+	backend := NewBackendHealthCheck(Options{
+		Path:    "/",
+		Timeout: 50 * time.Millisecond,
+	})
+	if backend.checkServer(serverURL) {
+		t.Fatal("expected server to be unhealthy after timeout")
+	}
+}
@@ -0,0 +1,363 @@
+package middlewares
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultAuthRealm = "traefik"
+	digestNonceTTL   = 5 * time.Minute
+)
+
+// BasicAuth is a middleware checking HTTP basic authentication credentials
+// against htpasswd entries.
+type BasicAuth struct {
+	realm string
+	users map[string]string
+}
+
+// NewBasicAuth returns a new BasicAuth from htpasswd entries (user:hash),
+// given inline or read from usersFile.
+// Supported hashes are bcrypt, MD5 (apr1) and SHA1.
+func NewBasicAuth(users []string, usersFile, realm string) (*BasicAuth, error) {
+	entries, err := loadAuthUsers(users, usersFile)
+	if err != nil {
+		return nil, err
+	}
+	basicAuth := &BasicAuth{
+		realm: realm,
+		users: make(map[string]string),
+	}
+	if len(basicAuth.realm) == 0 {
+		basicAuth.realm = defaultAuthRealm
+	}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("Error parsing basic auth user '%s': expected user:hash", entry)
+		}
+		if !isSupportedPasswordHash(parts[1]) {
+			return nil, fmt.Errorf("Unsupported password hash for basic auth user %s", parts[0])
+		}
+		basicAuth.users[parts[0]] = parts[1]
+	}
+	return basicAuth, nil
+}
+
+func (basicAuth *BasicAuth) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	user, password, ok := r.BasicAuth()
+	if ok {
+		if hash, exists := basicAuth.users[user]; exists && checkPassword(password, hash) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		log.Debugf("Basic auth failed for user %s", user)
+	}
+	rw.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", basicAuth.realm))
+	http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// DigestAuth is a middleware checking HTTP digest authentication (RFC 2617,
+// MD5 algorithm) credentials against htdigest entries. A nonce count can
+// only be used once with a nonce, so that a captured Authorization header
+// can't be replayed.
+type DigestAuth struct {
+	realm     string
+	users     map[string]string
+	secret    []byte
+	mutex     sync.Mutex
+	nonces    map[string]*digestNonce
+	lastSweep time.Time
+}
+
+// digestNonce holds the nonce counts already used with a nonce.
+type digestNonce struct {
+	created time.Time
+	counts  map[uint64]bool
+}
+
+// NewDigestAuth returns a new DigestAuth from htdigest entries
+// (user:realm:hash), given inline or read from usersFile.
+// Only entries of the given realm are used.
+func NewDigestAuth(users []string, usersFile, realm string) (*DigestAuth, error) {
+	entries, err := loadAuthUsers(users, usersFile)
+	if err != nil {
+		return nil, err
+	}
+	digestAuth := &DigestAuth{
+		realm:  realm,
+		users:  make(map[string]string),
+		secret: make([]byte, 32),
+		nonces: make(map[string]*digestNonce),
+	}
+	if len(digestAuth.realm) == 0 {
+		digestAuth.realm = defaultAuthRealm
+	}
+	if _, err := rand.Read(digestAuth.secret); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("Error parsing digest auth user '%s': expected user:realm:hash", entry)
+		}
+		if parts[1] == digestAuth.realm {
+			digestAuth.users[parts[0]] = parts[2]
+		}
+	}
+	return digestAuth, nil
+}
+
+func (digestAuth *DigestAuth) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	stale := false
+	if params := parseDigestAuthorization(r.Header.Get("Authorization")); params != nil {
+		if ha1, exists := digestAuth.users[params["username"]]; exists && params["realm"] == digestAuth.realm && params["uri"] == originalRequestURI(r) {
+			if expected := digestResponse(ha1, r.Method, params); subtle.ConstantTimeCompare([]byte(expected), []byte(params["response"])) == 1 {
+				if digestAuth.checkNonce(params["nonce"], params["nc"]) {
+					next.ServeHTTP(rw, r)
+					return
+				}
+				stale = true
+			}
+		}
+		log.Debugf("Digest auth failed for user %s", params["username"])
+	}
+	challenge := fmt.Sprintf(`Digest realm=%q, nonce=%q, algorithm=MD5, qop="auth"`, digestAuth.realm, digestAuth.newNonce())
+	if stale {
+		challenge += ", stale=true"
+	}
+	rw.Header().Set("WWW-Authenticate", challenge)
+	http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// newNonce returns a nonce made of its creation time signed with the
+// middleware secret, so that no state has to be kept between requests.
+func (digestAuth *DigestAuth) newNonce() string {
+	timestamp := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestamp, uint64(time.Now().UnixNano()))
+	mac := hmac.New(sha1.New, digestAuth.secret)
+	mac.Write(timestamp)
+	return base64.RawURLEncoding.EncodeToString(append(timestamp, mac.Sum(nil)...))
+}
+
+// checkNonce returns whether a nonce was created by the middleware and has
+// not expired, and whether its nonce count was not used yet.
+func (digestAuth *DigestAuth) checkNonce(nonce, nc string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(raw) <= 8 {
+		return false
+	}
+	mac := hmac.New(sha1.New, digestAuth.secret)
+	mac.Write(raw[:8])
+	if !hmac.Equal(mac.Sum(nil), raw[8:]) {
+		return false
+	}
+	created := time.Unix(0, int64(binary.BigEndian.Uint64(raw[:8])))
+	if time.Since(created) >= digestNonceTTL {
+		return false
+	}
+	// a request without qop has no nonce count: its nonce can be used once
+	var count uint64
+	if len(nc) > 0 {
+		if count, err = strconv.ParseUint(nc, 16, 64); err != nil {
+			return false
+		}
+	}
+
+	digestAuth.mutex.Lock()
+	defer digestAuth.mutex.Unlock()
+	now := time.Now()
+	if now.Sub(digestAuth.lastSweep) > digestNonceTTL {
+		for key, used := range digestAuth.nonces {
+			if now.Sub(used.created) >= digestNonceTTL {
+				delete(digestAuth.nonces, key)
+			}
+		}
+		digestAuth.lastSweep = now
+	}
+	used, ok := digestAuth.nonces[nonce]
+	if !ok {
+		used = &digestNonce{created: created, counts: make(map[uint64]bool)}
+		digestAuth.nonces[nonce] = used
+	}
+	if used.counts[count] {
+		log.Debugf("Digest auth nonce count %s replayed", nc)
+		return false
+	}
+	used.counts[count] = true
+	return true
+}
+
+func digestResponse(ha1, method string, params map[string]string) string {
+	ha2 := md5Hex(method + ":" + params["uri"])
+	if len(params["qop"]) == 0 {
+		return md5Hex(ha1 + ":" + params["nonce"] + ":" + ha2)
+	}
+	return md5Hex(strings.Join([]string{ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2}, ":"))
+}
+
+// parseDigestAuthorization returns the parameters of a Digest Authorization
+// header, or nil if the header is not a Digest one.
+func parseDigestAuthorization(header string) map[string]string {
+	const prefix = "Digest "
+	if !strings.HasPrefix(header, prefix) {
+		return nil
+	}
+	params := make(map[string]string)
+	rest := header[len(prefix):]
+	for len(rest) > 0 {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return nil
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+		params[key] = value
+	}
+	return params
+}
+
+func loadAuthUsers(users []string, usersFile string) ([]string, error) {
+	entries := append([]string{}, users...)
+	if len(usersFile) == 0 {
+		return entries, nil
+	}
+	file, err := os.Open(usersFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func isSupportedPasswordHash(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$", "$apr1$", "{SHA}"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPassword(password, hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hash[len("{SHA}"):]), []byte(base64.StdEncoding.EncodeToString(sum[:]))) == 1
+	case strings.HasPrefix(hash, "$apr1$"):
+		salt := strings.SplitN(hash[len("$apr1$"):], "$", 2)[0]
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(apr1(password, salt))) == 1
+	default:
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+}
+
+// apr1 computes the Apache MD5 crypt of a password, as written by htpasswd -m.
+func apr1(password, salt string) string {
+	const magic = "$apr1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alternate := md5.Sum([]byte(password + salt + password))
+	ctx := md5.New()
+	ctx.Write([]byte(password + magic + salt))
+	for i := len(pw); i > 0; i -= 16 {
+		if i > 16 {
+			ctx.Write(alternate[:])
+		} else {
+			ctx.Write(alternate[:i])
+		}
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 == 1 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(pw[:1])
+		}
+	}
+	final := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 == 1 {
+			round.Write(pw)
+		} else {
+			round.Write(final)
+		}
+		if i%3 != 0 {
+			round.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			round.Write(pw)
+		}
+		if i&1 == 1 {
+			round.Write(final)
+		} else {
+			round.Write(pw)
+		}
+		final = round.Sum(nil)
+	}
+
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	result := []byte(magic + salt + "$")
+	encode := func(value uint, n int) {
+		for ; n > 0; n-- {
+			result = append(result, itoa64[value&0x3f])
+			value >>= 6
+		}
+	}
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint(final[group[0]])<<16|uint(final[group[1]])<<8|uint(final[group[2]]), 4)
+	}
+	encode(uint(final[11]), 2)
+	return string(result)
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newAuthServer(handler negroni.Handler) *httptest.Server {
+	n := negroni.New(handler)
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "traefik")
+	}))
+	return httptest.NewServer(n)
+}
+
+func TestCheckPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	hashes := []string{
+		string(bcryptHash),
+		"$apr1$saltsalt$yAAkm4libquA.ZWLHbSBq/",
+		"{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+	}
+	for _, hash := range hashes {
+		assert.True(t, checkPassword("password", hash), hash)
+		assert.False(t, checkPassword("wrong", hash), hash)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	usersFile, err := ioutil.TempFile("", "traefik-basic-auth")
+	assert.NoError(t, err)
+	defer os.Remove(usersFile.Name())
+	_, err = usersFile.WriteString("# users\nfile:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")
+	assert.NoError(t, err)
+	usersFile.Close()
+
+	basicAuth, err := NewBasicAuth([]string{"test:$apr1$saltsalt$yAAkm4libquA.ZWLHbSBq/"}, usersFile.Name(), "")
+	assert.NoError(t, err)
+	ts := newAuthServer(basicAuth)
+	defer ts.Close()
+
+	cases := []struct {
+		user, password string
+		expected       int
+	}{
+		{"test", "password", http.StatusOK},
+		{"file", "password", http.StatusOK},
+		{"test", "wrong", http.StatusUnauthorized},
+		{"unknown", "password", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req.SetBasicAuth(c.user, c.password)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, resp.StatusCode, c.user)
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Basic realm="traefik"`, resp.Header.Get("WWW-Authenticate"))
+}
+
+func TestBasicAuthInvalidUsers(t *testing.T) {
+	_, err := NewBasicAuth([]string{"test"}, "", "")
+	assert.Error(t, err)
+	_, err = NewBasicAuth([]string{"test:plaintext"}, "", "")
+	assert.Error(t, err)
+}
+
+func TestDigestAuth(t *testing.T) {
+	// htdigest entry for test:traefik:test
+	digestAuth, err := NewDigestAuth([]string{"test:traefik:a2688e031edb4be6a3797f3882655c05"}, "", "")
+	assert.NoError(t, err)
+	ts := newAuthServer(digestAuth)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/foo")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	challenge := parseDigestAuthorization(resp.Header.Get("WWW-Authenticate"))
+	assert.NotNil(t, challenge)
+	assert.Equal(t, "traefik", challenge["realm"])
+
+	cases := []struct {
+		password, nonce, nc string
+		expected            int
+	}{
+		{"test", challenge["nonce"], "00000001", http.StatusOK},
+		{"wrong", challenge["nonce"], "00000002", http.StatusUnauthorized},
+		{"test", "forged", "00000002", http.StatusUnauthorized},
+		{"test", challenge["nonce"], "00000002", http.StatusOK},
+		{"test", challenge["nonce"], "00000001", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", ts.URL+"/foo", nil)
+		req.Header.Set("Authorization", digestAuthorization(c.password, c.nonce, c.nc, "/foo"))
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, resp.StatusCode, c.password+" "+c.nonce+" "+c.nc)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if c.expected == http.StatusOK {
+			assert.Equal(t, "traefik", string(body))
+		} else {
+			assert.True(t, strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Digest "))
+		}
+	}
+}
+
+func TestDigestAuthStripPrefix(t *testing.T) {
+	digestAuth, err := NewDigestAuth([]string{"test:traefik:a2688e031edb4be6a3797f3882655c05"}, "", "")
+	assert.NoError(t, err)
+	n := negroni.New(digestAuth)
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, r.RequestURI)
+	}))
+	ts := httptest.NewServer(&StripPrefix{Prefixes: []string{"/api"}, Handler: n})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/foo")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	challenge := parseDigestAuthorization(resp.Header.Get("WWW-Authenticate"))
+	assert.NotNil(t, challenge)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/api/foo", nil)
+	req.Header.Set("Authorization", digestAuthorization("test", challenge["nonce"], "00000001", "/api/foo"))
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/foo", string(body))
+}
+
+// digestAuthorization returns the Digest Authorization header of user test
+// for a GET request.
+func digestAuthorization(password, nonce, nc, uri string) string {
+	params := map[string]string{"uri": uri, "nonce": nonce, "nc": nc, "cnonce": "0a4f113b", "qop": "auth"}
+	response := digestResponse(md5Hex("test:traefik:"+password), "GET", params)
+	return fmt.Sprintf(`Digest username="test", realm="traefik", nonce=%q, uri=%q, qop=auth, nc=%s, cnonce="0a4f113b", response=%q`, nonce, uri, nc, response)
+}
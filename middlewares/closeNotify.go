@@ -0,0 +1,14 @@
+package middlewares
+
+import (
+	"net/http"
+)
+
+// closeNotify returns the close notification channel of rw, or a channel that
+// never fires if rw is not an http.CloseNotifier, like the access log writer.
+func closeNotify(rw http.ResponseWriter) <-chan bool {
+	if closeNotifier, ok := rw.(http.CloseNotifier); ok {
+		return closeNotifier.CloseNotify()
+	}
+	return make(chan bool)
+}
@@ -0,0 +1,253 @@
+package middlewares
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the minimum body size for a response to be compressed.
+const compressMinSize = 1024
+
+// brotliLevel is the brotli compression level, favoring speed as responses
+// are compressed on the fly.
+const brotliLevel = 4
+
+// compressEncodings are the supported content encodings, by order of
+// preference when a client accepts several of them with the same quality.
+var compressEncodings = []string{"br", "gzip"}
+
+// uncompressibleContentTypes are the content types (or prefixes, ending
+// with /) that are already compressed.
+var uncompressibleContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/x-compress",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/font-woff",
+	"application/font-woff2",
+	"font/woff",
+	"font/woff2",
+}
+
+// Compress is a middleware compressing responses with brotli or gzip, for
+// clients accepting them. Responses already encoded, partial, with an already
+// compressed content type, or smaller than compressMinSize are sent as is.
+type Compress struct{}
+
+// NewCompress returns a new Compress.
+func NewCompress() *Compress {
+	return &Compress{}
+}
+
+func (c *Compress) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if r.Method == "HEAD" || len(r.Header.Get("Upgrade")) > 0 || len(encoding) == 0 {
+		next.ServeHTTP(rw, r)
+		return
+	}
+	crw := &compressResponseWriter{rw: rw, encoding: encoding}
+	// the compressed stream must be complete before returning, so that
+	// outer writers (like the access log one) see every written byte
+	defer crw.close()
+	next.ServeHTTP(crw, r)
+}
+
+// negotiateEncoding returns the supported encoding with the highest non zero
+// quality in an Accept-Encoding header, or an empty string if none of them
+// is accepted.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(encoding, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if len(name) == 0 {
+			continue
+		}
+		quality := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		qualities[name] = quality
+	}
+	negotiated, negotiatedQuality := "", 0.0
+	for _, encoding := range compressEncodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > negotiatedQuality {
+			negotiated, negotiatedQuality = encoding, quality
+		}
+	}
+	return negotiated
+}
+
+// compressWriter is implemented by the gzip and brotli writers.
+type compressWriter interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// compressResponseWriter buffers the beginning of the body until it knows
+// whether to compress the response, i.e. until compressMinSize bytes are
+// written, the response is flushed or the handler returns.
+type compressResponseWriter struct {
+	rw       http.ResponseWriter
+	encoding string
+	code     int
+	buf      []byte
+	cw       compressWriter
+	decided  bool
+}
+
+func (crw *compressResponseWriter) Header() http.Header {
+	return crw.rw.Header()
+}
+
+func (crw *compressResponseWriter) WriteHeader(code int) {
+	if crw.code != 0 {
+		return
+	}
+	crw.code = code
+	// responses without body are never compressed, nor partial responses
+	// whose range applies to the uncompressed body
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
+		crw.decide(false)
+	}
+}
+
+func (crw *compressResponseWriter) Write(b []byte) (int, error) {
+	if crw.code == 0 {
+		crw.WriteHeader(http.StatusOK)
+	}
+	if !crw.decided {
+		crw.buf = append(crw.buf, b...)
+		if len(crw.buf) < compressMinSize {
+			return len(b), nil
+		}
+		if err := crw.decide(crw.isCompressible()); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if crw.cw != nil {
+		return crw.cw.Write(b)
+	}
+	return crw.rw.Write(b)
+}
+
+func (crw *compressResponseWriter) Flush() {
+	if !crw.decided {
+		if crw.code == 0 {
+			crw.WriteHeader(http.StatusOK)
+		}
+		// a streamed response may be compressed even if its first chunk is small
+		crw.decide(crw.isCompressible() && len(crw.Header().Get("Content-Length")) == 0)
+	}
+	if crw.cw != nil {
+		crw.cw.Flush()
+	}
+	if f, ok := crw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (crw *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return crw.rw.(http.Hijacker).Hijack()
+}
+
+func (crw *compressResponseWriter) CloseNotify() <-chan bool {
+	return closeNotify(crw.rw)
+}
+
+func (crw *compressResponseWriter) isCompressible() bool {
+	header := crw.Header()
+	if len(header.Get("Content-Encoding")) > 0 || len(header.Get("Content-Range")) > 0 {
+		return false
+	}
+	if contentLength, err := strconv.Atoi(header.Get("Content-Length")); err == nil && contentLength < compressMinSize {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if len(contentType) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, uncompressible := range uncompressibleContentTypes {
+		if mediaType == uncompressible || strings.HasSuffix(uncompressible, "/") && strings.HasPrefix(mediaType, uncompressible) {
+			return mediaType == "image/svg+xml"
+		}
+	}
+	return true
+}
+
+// decide writes the response header and the buffered body, compressed or not.
+func (crw *compressResponseWriter) decide(compress bool) error {
+	crw.decided = true
+	header := crw.Header()
+	if compress {
+		if len(header.Get("Content-Type")) == 0 {
+			// the content type can't be sniffed from the compressed body
+			header.Set("Content-Type", http.DetectContentType(crw.buf))
+		}
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", crw.encoding)
+		header.Add("Vary", "Accept-Encoding")
+	}
+	crw.rw.WriteHeader(crw.code)
+	if compress {
+		if crw.encoding == "br" {
+			crw.cw = brotli.NewWriterLevel(crw.rw, brotliLevel)
+		} else {
+			crw.cw = gzip.NewWriter(crw.rw)
+		}
+	}
+	buf := crw.buf
+	crw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if crw.cw != nil {
+		_, err = crw.cw.Write(buf)
+	} else {
+		_, err = crw.rw.Write(buf)
+	}
+	return err
+}
+
+func (crw *compressResponseWriter) close() {
+	if !crw.decided {
+		if crw.code == 0 {
+			// nothing was written by the handler
+			return
+		}
+		crw.decide(false)
+	}
+	if crw.cw != nil {
+		crw.cw.Close()
+	}
+}
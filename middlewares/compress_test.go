@@ -0,0 +1,187 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveCompressed(acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	n := negroni.New(NewCompress())
+	n.UseHandler(handler)
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	if len(acceptEncoding) > 0 {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	recorder := httptest.NewRecorder()
+	n.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("Hello, World ", 200)
+	recorder := serveCompressed("deflate, gzip", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Header().Set("Content-Length", "2600")
+		rw.Write([]byte(body[:100]))
+		rw.Write([]byte(body[100:]))
+	})
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+	assert.Empty(t, recorder.Header().Get("Content-Length"))
+	assert.True(t, recorder.Body.Len() < len(body))
+	reader, err := gzip.NewReader(bytes.NewReader(recorder.Body.Bytes()))
+	if assert.NoError(t, err) {
+		uncompressed, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(uncompressed))
+	}
+}
+
+func TestCompressBrotli(t *testing.T) {
+	body := strings.Repeat("Hello, World ", 200)
+	recorder := serveCompressed("gzip, deflate, br", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Write([]byte(body))
+	})
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "br", recorder.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+	assert.True(t, recorder.Body.Len() < len(body))
+	uncompressed, err := ioutil.ReadAll(brotli.NewReader(bytes.NewReader(recorder.Body.Bytes())))
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(uncompressed))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"deflate", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, br", "br"},
+		{"gzip;q=1, br;q=0.5", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"gzip;q=0", ""},
+		{"*", "br"},
+		{"*, br;q=0", "gzip"},
+		{"GZIP", "gzip"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, negotiateEncoding(c.acceptEncoding), c.acceptEncoding)
+	}
+}
+
+func TestCompressSkipped(t *testing.T) {
+	largeBody := strings.Repeat("Hello, World ", 200)
+	cases := []struct {
+		desc           string
+		acceptEncoding string
+		contentType    string
+		encoding       string
+		body           string
+	}{
+		{"gzip not accepted", "deflate", "text/plain", "", largeBody},
+		{"gzip refused", "gzip;q=0", "text/plain", "", largeBody},
+		{"small body", "gzip", "text/plain", "", "Hello, World"},
+		{"compressed content type", "gzip", "image/png", "", largeBody},
+		{"already encoded", "gzip", "text/plain", "br", largeBody},
+	}
+
+	for _, c := range cases {
+		recorder := serveCompressed(c.acceptEncoding, func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Content-Type", c.contentType)
+			if len(c.encoding) > 0 {
+				rw.Header().Set("Content-Encoding", c.encoding)
+			}
+			rw.WriteHeader(http.StatusAccepted)
+			rw.Write([]byte(c.body))
+		})
+		assert.Equal(t, http.StatusAccepted, recorder.Code, c.desc)
+		assert.Equal(t, c.encoding, recorder.Header().Get("Content-Encoding"), c.desc)
+		assert.Equal(t, c.body, recorder.Body.String(), c.desc)
+	}
+}
+
+func TestCompressPartialContent(t *testing.T) {
+	body := strings.Repeat("Hello, World ", 200)
+	cases := []struct {
+		desc string
+		code int
+	}{
+		{"partial content", http.StatusPartialContent},
+		{"content range", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		recorder := serveCompressed("gzip, br", func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Content-Type", "text/plain")
+			rw.Header().Set("Content-Range", "bytes 0-2599/5200")
+			rw.WriteHeader(c.code)
+			rw.Write([]byte(body))
+		})
+		assert.Equal(t, c.code, recorder.Code, c.desc)
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"), c.desc)
+		assert.Equal(t, body, recorder.Body.String(), c.desc)
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	var flushedBody []byte
+	n := negroni.New(NewCompress())
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Write([]byte("data: Hello, World\n\n"))
+		rw.(http.Flusher).Flush()
+		flushedBody = append(flushedBody, recorder.Body.Bytes()...)
+	}))
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	n.ServeHTTP(recorder, req)
+
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	// the first event must be readable before the response is complete
+	reader, err := gzip.NewReader(bytes.NewReader(flushedBody))
+	if assert.NoError(t, err) {
+		event := make([]byte, 20)
+		_, err := io.ReadFull(reader, event)
+		assert.NoError(t, err)
+		assert.Equal(t, "data: Hello, World\n\n", string(event))
+	}
+}
+
+func TestCompressLoggedSize(t *testing.T) {
+	body := strings.Repeat("Hello, World ", 200)
+	infoRw := &logInfoResponseWriter{rw: httptest.NewRecorder()}
+	n := negroni.New(NewCompress())
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(body))
+	}))
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	n.ServeHTTP(infoRw, req)
+
+	// the access log records the size of the compressed body, gzip footer included
+	recorder := infoRw.rw.(*httptest.ResponseRecorder)
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	assert.Equal(t, recorder.Body.Len(), infoRw.GetSize())
+	assert.True(t, infoRw.GetSize() < len(body))
+}
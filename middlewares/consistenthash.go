@@ -0,0 +1,181 @@
+package middlewares
+
+import (
+	"errors"
+	"hash/crc32"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+// consistentHashReplicas is the number of points of each server on the ring.
+const consistentHashReplicas = 160
+
+const cookieHashKeyPrefix = "request.cookie."
+
+// ConsistentHash is a load-balancer forwarding the requests with the same
+// key to the same server, as long as it's available. Servers are placed on a
+// hash ring, so that adding or removing a server only moves the keys of this
+// server. Servers weights are ignored.
+type ConsistentHash struct {
+	next    http.Handler
+	key     func(r *http.Request) string
+	mutex   sync.RWMutex
+	servers []*url.URL
+	ring    []uint32
+	points  map[uint32]*url.URL
+}
+
+// NewConsistentHash returns a new ConsistentHash forwarding requests to next,
+// with the URL of the chosen server. The key of a request is given by
+// hashKey: client.ip (default), request.host, request.header.<name> or
+// request.cookie.<name>. The client IP is used for requests without the
+// header or cookie.
+func NewConsistentHash(next http.Handler, hashKey string) (*ConsistentHash, error) {
+	ch := &ConsistentHash{next: next, points: make(map[uint32]*url.URL)}
+	if len(hashKey) == 0 {
+		hashKey = "client.ip"
+	}
+	if strings.HasPrefix(hashKey, cookieHashKeyPrefix) {
+		cookieName := strings.TrimPrefix(hashKey, cookieHashKeyPrefix)
+		ch.key = func(r *http.Request) string {
+			if cookie, err := r.Cookie(cookieName); err == nil {
+				return cookie.Value
+			}
+			return ""
+		}
+		return ch, nil
+	}
+	extractor, err := utils.NewExtractor(hashKey)
+	if err != nil {
+		return nil, err
+	}
+	ch.key = func(r *http.Request) string {
+		key, _, err := extractor.Extract(r)
+		if err != nil {
+			return ""
+		}
+		return key
+	}
+	return ch, nil
+}
+
+func (ch *ConsistentHash) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	key := ch.key(r)
+	if len(key) == 0 {
+		key = clientIP(r)
+	}
+	server := ch.get(key)
+	if server == nil {
+		log.Errorf("No server available for request %v", r.URL)
+		http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	newReq := *r
+	newReq.URL = server
+	ch.next.ServeHTTP(rw, &newReq)
+}
+
+// get returns the server of the first point of the ring after the hash of
+// key, or nil if there is no server.
+func (ch *ConsistentHash) get(key string) *url.URL {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	if len(ch.ring) == 0 {
+		return nil
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ch.ring), func(i int) bool { return ch.ring[i] >= hash })
+	if i == len(ch.ring) {
+		i = 0
+	}
+	return ch.points[ch.ring[i]]
+}
+
+// Servers returns the URLs of the servers.
+func (ch *ConsistentHash) Servers() []*url.URL {
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
+	return append([]*url.URL{}, ch.servers...)
+}
+
+// UpsertServer adds a server, if not already there. Options are ignored.
+func (ch *ConsistentHash) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	if u == nil {
+		return errors.New("server URL can't be nil")
+	}
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	for _, server := range ch.servers {
+		if sameURL(server, u) {
+			return nil
+		}
+	}
+	ch.servers = append(ch.servers, copyURL(u))
+	ch.buildRing()
+	return nil
+}
+
+// RemoveServer removes a server, its keys being moved to the next servers on
+// the ring.
+func (ch *ConsistentHash) RemoveServer(u *url.URL) error {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	for i, server := range ch.servers {
+		if sameURL(server, u) {
+			ch.servers = append(ch.servers[:i], ch.servers[i+1:]...)
+			ch.buildRing()
+			return nil
+		}
+	}
+	return errors.New("server not found")
+}
+
+// buildRing places the points of every server on the ring. Points only
+// depend on the server URL, so the ring is the same whatever the order in
+// which servers are added.
+func (ch *ConsistentHash) buildRing() {
+	servers := append([]*url.URL{}, ch.servers...)
+	sort.Sort(urlsByString(servers))
+	ch.ring = nil
+	ch.points = make(map[uint32]*url.URL)
+	for _, server := range servers {
+		for replica := 0; replica < consistentHashReplicas; replica++ {
+			point := crc32.ChecksumIEEE([]byte(strconv.Itoa(replica) + "-" + server.String()))
+			if _, exists := ch.points[point]; exists {
+				continue
+			}
+			ch.points[point] = server
+			ch.ring = append(ch.ring, point)
+		}
+	}
+	sort.Sort(uint32s(ch.ring))
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type urlsByString []*url.URL
+
+func (u urlsByString) Len() int           { return len(u) }
+func (u urlsByString) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u urlsByString) Less(i, j int) bool { return u[i].String() < u[j].String() }
+
+type uint32s []uint32
+
+func (u uint32s) Len() int           { return len(u) }
+func (u uint32s) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u uint32s) Less(i, j int) bool { return u[i] < u[j] }
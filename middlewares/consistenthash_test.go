@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveConsistentHash(ch *ConsistentHash, configure func(r *http.Request)) string {
+	var host string
+	ch.next = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+	})
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	configure(req)
+	ch.ServeHTTP(httptest.NewRecorder(), req)
+	return host
+}
+
+func TestConsistentHashKeys(t *testing.T) {
+	cases := []struct {
+		hashKey   string
+		configure func(r *http.Request)
+	}{
+		{"", func(r *http.Request) {}},
+		{"request.host", func(r *http.Request) { r.Host = "foo.localhost" }},
+		{"request.header.X-User", func(r *http.Request) { r.Header.Set("X-User", "foo") }},
+		{"request.cookie.session", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "session", Value: "foo"}) }},
+	}
+
+	for _, c := range cases {
+		ch, err := NewConsistentHash(nil, c.hashKey)
+		assert.NoError(t, err, c.hashKey)
+		for i := 1; i <= 5; i++ {
+			server, _ := url.Parse(fmt.Sprintf("http://10.0.0.%d:80", i))
+			ch.UpsertServer(server)
+		}
+		host := serveConsistentHash(ch, c.configure)
+		assert.NotEmpty(t, host, c.hashKey)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, host, serveConsistentHash(ch, c.configure), c.hashKey)
+		}
+	}
+
+	_, err := NewConsistentHash(nil, "foo")
+	assert.Error(t, err)
+}
+
+func TestConsistentHashRemapping(t *testing.T) {
+	ch, _ := NewConsistentHash(nil, "request.header.X-User")
+	for i := 1; i <= 5; i++ {
+		server, _ := url.Parse(fmt.Sprintf("http://10.0.0.%d:80", i))
+		ch.UpsertServer(server)
+	}
+	hostsByUser := map[string]string{}
+	for i := 0; i < 1000; i++ {
+		user := fmt.Sprintf("user%d", i)
+		hostsByUser[user] = serveConsistentHash(ch, func(r *http.Request) { r.Header.Set("X-User", user) })
+	}
+
+	// only the keys of the removed server move
+	removed, _ := url.Parse("http://10.0.0.3:80")
+	assert.NoError(t, ch.RemoveServer(removed))
+	moved := 0
+	for user, host := range hostsByUser {
+		newHost := serveConsistentHash(ch, func(r *http.Request) { r.Header.Set("X-User", user) })
+		if host != removed.Host {
+			assert.Equal(t, host, newHost, user)
+		} else {
+			assert.NotEqual(t, host, newHost, user)
+			moved++
+		}
+	}
+	assert.True(t, moved > 100 && moved < 300, "%d keys moved", moved)
+
+	// and they go back once the server is added again
+	assert.NoError(t, ch.UpsertServer(removed))
+	for user, host := range hostsByUser {
+		assert.Equal(t, host, serveConsistentHash(ch, func(r *http.Request) { r.Header.Set("X-User", user) }), user)
+	}
+}
@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/types"
+	"github.com/ryanuber/go-glob"
+)
+
+var (
+	defaultCORSAllowedMethods = []string{"GET", "HEAD", "POST"}
+	defaultCORSAllowedHeaders = []string{"Origin", "Accept", "Content-Type", "X-Requested-With"}
+)
+
+// CORS is a middleware handling cross-origin resource sharing: it answers
+// preflight requests without calling the backend, and adds the CORS headers
+// to the responses of actual requests.
+type CORS struct {
+	allowedOrigins   []string
+	allowedMethods   []string
+	allowedHeaders   []string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           int
+}
+
+// NewCORS returns a new CORS from a frontend CORS configuration.
+func NewCORS(cors *types.CORS) *CORS {
+	c := &CORS{
+		allowedOrigins:   cors.AllowedOrigins,
+		allowedMethods:   defaultCORSAllowedMethods,
+		allowedHeaders:   defaultCORSAllowedHeaders,
+		exposedHeaders:   strings.Join(cors.ExposedHeaders, ", "),
+		allowCredentials: cors.AllowCredentials,
+		maxAge:           cors.MaxAge,
+	}
+	if len(cors.AllowedMethods) > 0 {
+		c.allowedMethods = nil
+		for _, method := range cors.AllowedMethods {
+			c.allowedMethods = append(c.allowedMethods, strings.ToUpper(strings.TrimSpace(method)))
+		}
+	}
+	if len(cors.AllowedHeaders) > 0 {
+		c.allowedHeaders = nil
+		for _, header := range cors.AllowedHeaders {
+			c.allowedHeaders = append(c.allowedHeaders, http.CanonicalHeaderKey(strings.TrimSpace(header)))
+		}
+	}
+	return c
+}
+
+func (c *CORS) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.Method == "OPTIONS" && len(r.Header.Get("Access-Control-Request-Method")) > 0 {
+		c.handlePreflight(rw, r)
+		return
+	}
+	origin := r.Header.Get("Origin")
+	rw.Header().Add("Vary", "Origin")
+	if len(origin) > 0 && c.isOriginAllowed(origin) {
+		c.setAllowOrigin(rw, origin)
+		if len(c.exposedHeaders) > 0 {
+			rw.Header().Set("Access-Control-Expose-Headers", c.exposedHeaders)
+		}
+	}
+	next.ServeHTTP(rw, r)
+}
+
+func (c *CORS) handlePreflight(rw http.ResponseWriter, r *http.Request) {
+	header := rw.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	requestedHeaders := parseHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+	if !c.isOriginAllowed(origin) || !c.isMethodAllowed(method) || !c.areHeadersAllowed(requestedHeaders) {
+		log.Debugf("CORS preflight request from origin %s for %s %v rejected", origin, method, requestedHeaders)
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	c.setAllowOrigin(rw, origin)
+	header.Set("Access-Control-Allow-Methods", method)
+	if len(requestedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+	}
+	if c.maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(c.maxAge))
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (c *CORS) setAllowOrigin(rw http.ResponseWriter, origin string) {
+	if c.allowCredentials {
+		rw.Header().Set("Access-Control-Allow-Credentials", "true")
+		rw.Header().Set("Access-Control-Allow-Origin", origin)
+	} else if c.allowsAllOrigins() {
+		rw.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		rw.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+}
+
+func (c *CORS) allowsAllOrigins() bool {
+	for _, allowedOrigin := range c.allowedOrigins {
+		if allowedOrigin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) isOriginAllowed(origin string) bool {
+	if len(origin) == 0 {
+		return false
+	}
+	origin = strings.ToLower(origin)
+	for _, allowedOrigin := range c.allowedOrigins {
+		if glob.Glob(strings.ToLower(allowedOrigin), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) isMethodAllowed(method string) bool {
+	if method == "OPTIONS" {
+		return true
+	}
+	for _, allowedMethod := range c.allowedMethods {
+		if allowedMethod == "*" || allowedMethod == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) areHeadersAllowed(requestedHeaders []string) bool {
+	for _, requestedHeader := range requestedHeaders {
+		allowed := false
+		for _, allowedHeader := range c.allowedHeaders {
+			if allowedHeader == "*" || allowedHeader == requestedHeader {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList returns the canonical header names of a comma separated
+// list, as sent in Access-Control-Request-Headers.
+func parseHeaderList(headerList string) []string {
+	var headers []string
+	for _, header := range strings.Split(headerList, ",") {
+		if header = strings.TrimSpace(header); len(header) > 0 {
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return headers
+}
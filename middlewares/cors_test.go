@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSHandler(cors *types.CORS, called *bool) http.Handler {
+	n := negroni.New(NewCORS(cors))
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		*called = true
+		rw.Write([]byte("traefik"))
+	}))
+	return n
+}
+
+func TestCORSPreflight(t *testing.T) {
+	cors := &types.CORS{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"GET", "put"},
+		AllowedHeaders:   []string{"Content-Type", "x-api-key"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	cases := []struct {
+		desc     string
+		origin   string
+		method   string
+		headers  string
+		expected int
+	}{
+		{"allowed", "https://app.example.com", "PUT", "X-Api-Key, content-type", http.StatusOK},
+		{"origin not allowed", "https://app.example.org", "PUT", "", http.StatusForbidden},
+		{"method not allowed", "https://app.example.com", "DELETE", "", http.StatusForbidden},
+		{"header not allowed", "https://app.example.com", "GET", "X-Other", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		called := false
+		handler := newCORSHandler(cors, &called)
+		req, _ := http.NewRequest("OPTIONS", "http://api.localhost/", nil)
+		req.Header.Set("Origin", c.origin)
+		req.Header.Set("Access-Control-Request-Method", c.method)
+		if len(c.headers) > 0 {
+			req.Header.Set("Access-Control-Request-Headers", c.headers)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.False(t, called, c.desc)
+		assert.Equal(t, c.expected, recorder.Code, c.desc)
+		if c.expected == http.StatusOK {
+			assert.Equal(t, c.origin, recorder.Header().Get("Access-Control-Allow-Origin"), c.desc)
+			assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"), c.desc)
+			assert.Equal(t, c.method, recorder.Header().Get("Access-Control-Allow-Methods"), c.desc)
+			assert.Equal(t, "X-Api-Key, Content-Type", recorder.Header().Get("Access-Control-Allow-Headers"), c.desc)
+			assert.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"), c.desc)
+		} else {
+			assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"), c.desc)
+		}
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	called := false
+	handler := newCORSHandler(&types.CORS{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Request-Id", "X-Total-Count"},
+	}, &called)
+
+	req, _ := http.NewRequest("GET", "http://api.localhost/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.True(t, called)
+	assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-Id, X-Total-Count", recorder.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "traefik", recorder.Body.String())
+
+	// OPTIONS requests that are not preflights are sent to the backend
+	called = false
+	req, _ = http.NewRequest("OPTIONS", "http://api.localhost/", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.True(t, called)
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,184 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// entityHeaders are the headers describing the body of a response, taken from
+// the error page instead of the original response.
+var entityHeaders = []string{
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Length",
+	"Content-Location",
+	"Content-MD5",
+	"Content-Range",
+	"Content-Type",
+	"Etag",
+	"Expires",
+	"Last-Modified",
+}
+
+// ErrorPages is a middleware replacing the body of the responses with some
+// status codes by a page requested to another backend. The original status
+// code is kept.
+type ErrorPages struct {
+	statusRanges   [][2]int
+	backendHandler http.Handler
+	query          string
+}
+
+// NewErrorPages returns a new ErrorPages. status is a list of status codes
+// or ranges (like 500-599), and query is the path requested to
+// backendHandler, where {status} is replaced by the original status code.
+func NewErrorPages(status []string, query string, backendHandler http.Handler) (*ErrorPages, error) {
+	if len(status) == 0 {
+		return nil, fmt.Errorf("No status for error pages")
+	}
+	errorPages := &ErrorPages{
+		backendHandler: backendHandler,
+		query:          query,
+	}
+	if len(errorPages.query) == 0 || errorPages.query[0] != '/' {
+		errorPages.query = "/" + errorPages.query
+	}
+	for _, statusRange := range status {
+		bounds := strings.SplitN(strings.TrimSpace(statusRange), "-", 2)
+		low, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid status range %s: %v", statusRange, err)
+		}
+		high := low
+		if len(bounds) == 2 {
+			if high, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("Invalid status range %s: %v", statusRange, err)
+			}
+		}
+		if low > high {
+			return nil, fmt.Errorf("Invalid status range %s", statusRange)
+		}
+		errorPages.statusRanges = append(errorPages.statusRanges, [2]int{low, high})
+	}
+	return errorPages, nil
+}
+
+func (ep *ErrorPages) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	eprw := &errorPagesResponseWriter{rw: rw, errorPages: ep}
+	next.ServeHTTP(eprw, r)
+	if !eprw.intercepted {
+		return
+	}
+
+	pageReq, err := http.NewRequest("GET", strings.Replace(ep.query, "{status}", strconv.Itoa(eprw.code), -1), nil)
+	if err != nil {
+		log.Errorf("Error creating error page request for status %d: %v", eprw.code, err)
+		eprw.writeOriginal()
+		return
+	}
+	// the forwarder sends RequestURI as is to the backend
+	pageReq.RequestURI = pageReq.URL.RequestURI()
+	pageReq.Host = r.Host
+	pageReq.RemoteAddr = r.RemoteAddr
+	pageReq.Header.Set("Accept", r.Header.Get("Accept"))
+	recorder := NewRecorder()
+	recorder.responseWriter = rw
+	ep.backendHandler.ServeHTTP(recorder, pageReq)
+	if recorder.Code != http.StatusOK {
+		log.Errorf("Error getting error page %s for status %d: got status %d", pageReq.URL, eprw.code, recorder.Code)
+		eprw.writeOriginal()
+		return
+	}
+
+	// only the headers describing the body are replaced, so that the headers
+	// set by the other middlewares of the frontend are kept
+	header := rw.Header()
+	for _, name := range entityHeaders {
+		header.Del(name)
+		if values, ok := recorder.Header()[name]; ok {
+			header[name] = append([]string(nil), values...)
+		}
+	}
+	header.Set("Content-Length", strconv.Itoa(recorder.Body.Len()))
+	rw.WriteHeader(eprw.code)
+	rw.Write(recorder.Body.Bytes())
+}
+
+func (ep *ErrorPages) matches(code int) bool {
+	for _, statusRange := range ep.statusRanges {
+		if code >= statusRange[0] && code <= statusRange[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// errorPagesResponseWriter holds back the responses whose status matches the
+// error pages, and lets the others go through untouched.
+type errorPagesResponseWriter struct {
+	rw          http.ResponseWriter
+	errorPages  *ErrorPages
+	code        int
+	wroteHeader bool
+	intercepted bool
+	body        bytes.Buffer
+}
+
+func (eprw *errorPagesResponseWriter) Header() http.Header {
+	return eprw.rw.Header()
+}
+
+func (eprw *errorPagesResponseWriter) WriteHeader(code int) {
+	if eprw.wroteHeader {
+		return
+	}
+	eprw.wroteHeader = true
+	eprw.code = code
+	if eprw.errorPages.matches(code) {
+		eprw.intercepted = true
+		return
+	}
+	eprw.rw.WriteHeader(code)
+}
+
+func (eprw *errorPagesResponseWriter) Write(b []byte) (int, error) {
+	if !eprw.wroteHeader {
+		eprw.WriteHeader(http.StatusOK)
+	}
+	if eprw.intercepted {
+		// kept to be sent if the error page can't be retrieved
+		return eprw.body.Write(b)
+	}
+	return eprw.rw.Write(b)
+}
+
+// writeOriginal sends the intercepted response as is.
+func (eprw *errorPagesResponseWriter) writeOriginal() {
+	eprw.rw.WriteHeader(eprw.code)
+	eprw.rw.Write(eprw.body.Bytes())
+}
+
+func (eprw *errorPagesResponseWriter) Flush() {
+	if eprw.intercepted {
+		return
+	}
+	if f, ok := eprw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (eprw *errorPagesResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return eprw.rw.(http.Hijacker).Hijack()
+}
+
+func (eprw *errorPagesResponseWriter) CloseNotify() <-chan bool {
+	return closeNotify(eprw.rw)
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPages(t *testing.T) {
+	pageBackend := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.html" {
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html")
+		rw.Header().Set("Set-Cookie", "page=1")
+		fmt.Fprintf(rw, "<h1>Error page %s</h1>", r.URL.Path)
+	})
+	cases := []struct {
+		desc                string
+		query               string
+		status              int
+		expectedBody        string
+		expectedContentType string
+	}{
+		{"status in range", "/{status}.html", http.StatusBadGateway, "<h1>Error page /502.html</h1>", "text/html"},
+		{"single status", "{status}.html", http.StatusNotFound, "<h1>Error page /404.html</h1>", "text/html"},
+		{"status not matching", "/{status}.html", http.StatusInternalServerError, "backend error", "text/plain"},
+		{"status ok", "/{status}.html", http.StatusOK, "backend error", "text/plain"},
+		{"error page not found", "/missing.html", http.StatusBadGateway, "backend error", "text/plain"},
+	}
+
+	for _, c := range cases {
+		errorPages, err := NewErrorPages([]string{"502-504", "404"}, c.query, pageBackend)
+		assert.NoError(t, err, c.desc)
+		n := negroni.New(negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+			rw.Header().Set("Strict-Transport-Security", "max-age=31536000")
+			next(rw, r)
+		}), errorPages)
+		n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Content-Type", "text/plain")
+			rw.Header().Set("Etag", `"backend"`)
+			rw.WriteHeader(c.status)
+			rw.Write([]byte("backend error"))
+		}))
+		req, _ := http.NewRequest("GET", "http://localhost/test", nil)
+		recorder := httptest.NewRecorder()
+		n.ServeHTTP(recorder, req)
+		assert.Equal(t, c.status, recorder.Code, c.desc)
+		assert.Equal(t, c.expectedBody, recorder.Body.String(), c.desc)
+		assert.Equal(t, c.expectedContentType, recorder.Header().Get("Content-Type"), c.desc)
+		assert.Equal(t, "max-age=31536000", recorder.Header().Get("Strict-Transport-Security"), c.desc)
+		assert.Empty(t, recorder.Header().Get("Set-Cookie"), c.desc)
+		if c.expectedContentType == "text/html" {
+			assert.Empty(t, recorder.Header().Get("Etag"), c.desc)
+		}
+	}
+}
+
+func TestNewErrorPagesErrors(t *testing.T) {
+	_, err := NewErrorPages(nil, "/", http.NotFoundHandler())
+	assert.Error(t, err)
+	_, err = NewErrorPages([]string{"foo"}, "/", http.NotFoundHandler())
+	assert.Error(t, err)
+	_, err = NewErrorPages([]string{"500-foo"}, "/", http.NotFoundHandler())
+	assert.Error(t, err)
+	_, err = NewErrorPages([]string{"599-500"}, "/", http.NotFoundHandler())
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package middlewares
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/vulcand/oxy/forward"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	xForwardedMethod = "X-Forwarded-Method"
+	xForwardedURI    = "X-Forwarded-Uri"
+)
+
+// forwardAuthTransport is shared by the ForwardAuth middlewares, which are
+// recreated on each configuration reload, so that the keep-alive connections
+// to the authentication services are reused instead of piling up.
+// Redirects of the authentication services are sent back to the clients, so
+// the transport is used directly instead of an http.Client.
+var forwardAuthTransport http.RoundTripper = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	Dial: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).Dial,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 30 * time.Second,
+}
+
+// ForwardAuth is a middleware delegating the authentication of requests to
+// an external service.
+type ForwardAuth struct {
+	address             string
+	authResponseHeaders []string
+	transport           http.RoundTripper
+}
+
+// NewForwardAuth returns a new ForwardAuth sending the request headers to
+// address. On a 2xx answer, the authResponseHeaders of the answer are copied
+// onto the request, which is forwarded. Otherwise, the answer of the
+// authentication service is returned to the client.
+func NewForwardAuth(address string, authResponseHeaders []string) (*ForwardAuth, error) {
+	authURL, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if authURL.Scheme != "http" && authURL.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid forward auth address %s: scheme must be http or https", address)
+	}
+	return &ForwardAuth{
+		address:             address,
+		authResponseHeaders: authResponseHeaders,
+		transport:           forwardAuthTransport,
+	}, nil
+}
+
+func (forwardAuth *ForwardAuth) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	authReq, err := http.NewRequest("GET", forwardAuth.address, nil)
+	if err != nil {
+		log.Errorf("Error creating forward auth request to %s: %v", forwardAuth.address, err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	utils.CopyHeaders(authReq.Header, r.Header)
+	utils.RemoveHeaders(authReq.Header, forward.HopHeaders...)
+	setForwardAuthHeaders(authReq, r)
+
+	authResp, err := forwardAuth.transport.RoundTrip(authReq)
+	if err != nil {
+		log.Errorf("Error calling forward auth service %s: %v", forwardAuth.address, err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer authResp.Body.Close()
+
+	if authResp.StatusCode < http.StatusOK || authResp.StatusCode >= http.StatusMultipleChoices {
+		log.Debugf("Forward auth service %s denied request %s with status %d", forwardAuth.address, r.URL, authResp.StatusCode)
+		utils.CopyHeaders(rw.Header(), authResp.Header)
+		utils.RemoveHeaders(rw.Header(), forward.HopHeaders...)
+		rw.WriteHeader(authResp.StatusCode)
+		io.Copy(rw, authResp.Body)
+		return
+	}
+
+	for _, header := range forwardAuth.authResponseHeaders {
+		// never trust a value sent by the client for these headers
+		r.Header.Del(header)
+		if values, ok := authResp.Header[http.CanonicalHeaderKey(header)]; ok {
+			r.Header[http.CanonicalHeaderKey(header)] = values
+		}
+	}
+	next.ServeHTTP(rw, r)
+}
+
+func setForwardAuthHeaders(authReq *http.Request, r *http.Request) {
+	authReq.Header.Set(xForwardedMethod, r.Method)
+	authReq.Header.Set(forward.XForwardedHost, r.Host)
+	authReq.Header.Set(xForwardedURI, r.URL.RequestURI())
+	if r.TLS != nil {
+		authReq.Header.Set(forward.XForwardedProto, "https")
+	} else {
+		authReq.Header.Set(forward.XForwardedProto, "http")
+	}
+	if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := r.Header.Get(forward.XForwardedFor); len(prior) > 0 {
+			clientIP = strings.Join([]string{prior, clientIP}, ", ")
+		}
+		authReq.Header.Set(forward.XForwardedFor, clientIP)
+	}
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardAuth(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			rw.Header().Set("Location", "http://sso.localhost/login?rd="+r.Header.Get(xForwardedURI))
+			rw.WriteHeader(http.StatusFound)
+			fmt.Fprint(rw, "login required")
+			return
+		}
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "POST", r.Header.Get(xForwardedMethod))
+		assert.Equal(t, "/foo?bar=1", r.Header.Get(xForwardedURI))
+		rw.Header().Set("X-Auth-User", "test")
+		rw.Header().Add("X-Auth-Groups", "admin")
+		rw.Header().Add("X-Auth-Groups", "dev")
+		rw.Header().Set("X-Auth-Secret", "secret")
+	}))
+	defer authServer.Close()
+
+	forwardAuth, err := NewForwardAuth(authServer.URL, []string{"X-Auth-User", "X-Auth-Groups", "X-Auth-Missing"})
+	assert.NoError(t, err)
+	n := negroni.New(forwardAuth)
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test", r.Header.Get("X-Auth-User"))
+		assert.Equal(t, []string{"admin", "dev"}, r.Header["X-Auth-Groups"])
+		assert.Empty(t, r.Header.Get("X-Auth-Missing"))
+		assert.Empty(t, r.Header.Get("X-Auth-Secret"))
+		fmt.Fprint(rw, "traefik")
+	}))
+	ts := httptest.NewServer(n)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("POST", ts.URL+"/foo?bar=1", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Auth-Missing", "spoofed")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "traefik", string(body))
+
+	transport := &http.Transport{}
+	req, _ = http.NewRequest("GET", ts.URL+"/foo", nil)
+	resp, err = transport.RoundTrip(req)
+	assert.NoError(t, err)
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "http://sso.localhost/login?rd=/foo", resp.Header.Get("Location"))
+	assert.Equal(t, "login required", string(body))
+}
+
+func TestForwardAuthUnreachable(t *testing.T) {
+	authServer := httptest.NewServer(http.NotFoundHandler())
+	authServer.Close()
+
+	forwardAuth, err := NewForwardAuth(authServer.URL, nil)
+	assert.NoError(t, err)
+	n := negroni.New(forwardAuth)
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not be forwarded")
+	}))
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/foo", nil)
+	n.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestNewForwardAuthInvalidAddress(t *testing.T) {
+	_, err := NewForwardAuth("localhost:9000", nil)
+	assert.Error(t, err)
+}
+
+func TestForwardAuthSharedTransport(t *testing.T) {
+	first, err := NewForwardAuth("http://auth.localhost/verify", nil)
+	assert.NoError(t, err)
+	reloaded, err := NewForwardAuth("http://auth.localhost/verify", nil)
+	assert.NoError(t, err)
+	assert.True(t, first.transport == reloaded.transport, "forward auth middlewares should share their transport across reloads")
+}
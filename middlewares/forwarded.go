@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// forwardedHeaders are the headers describing the proxies a request went
+// through, which can only be trusted when set by trusted proxies.
+var forwardedHeaders = []string{
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Forwarded-Host",
+	"X-Forwarded-Port",
+	"X-Forwarded-Proto",
+	"X-Forwarded-Server",
+	"X-Real-Ip",
+}
+
+// ForwardedHeaders is a middleware removing the forwarded headers of the
+// requests from untrusted peers, so that the forwarder sets them from
+// scratch. The headers of the requests from trusted peers are kept, the
+// forwarder appending the peer to them.
+// It can also add the peer to the RFC 7239 Forwarded header.
+type ForwardedHeaders struct {
+	trustedIPs []*net.IPNet
+	forwarded  bool
+}
+
+// NewForwardedHeaders returns a new ForwardedHeaders trusting the peers from
+// a list of CIDRs (a single IP is accepted as a /32 or /128 range), and
+// adding the Forwarded header if forwarded is true.
+func NewForwardedHeaders(trustedIPs []string, forwarded bool) (*ForwardedHeaders, error) {
+	forwardedHeaders := &ForwardedHeaders{forwarded: forwarded}
+	for _, trustedIP := range trustedIPs {
+		ipNet, err := parseIPRange(trustedIP)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trusted IP %s: %v", trustedIP, err)
+		}
+		forwardedHeaders.trustedIPs = append(forwardedHeaders.trustedIPs, ipNet)
+	}
+	return forwardedHeaders, nil
+}
+
+func (f *ForwardedHeaders) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !f.isTrusted(ip) {
+		for _, header := range forwardedHeaders {
+			r.Header.Del(header)
+		}
+	}
+	if f.forwarded {
+		element := "for=" + forwardedNode(ip, host)
+		if len(r.Host) > 0 {
+			element += ";host=" + quoteForwarded(r.Host)
+		}
+		if r.TLS != nil {
+			element += ";proto=https"
+		} else {
+			element += ";proto=http"
+		}
+		if previous := r.Header["Forwarded"]; len(previous) > 0 {
+			element = strings.Join(previous, ", ") + ", " + element
+		}
+		r.Header.Set("Forwarded", element)
+	}
+	next.ServeHTTP(rw, r)
+}
+
+func (f *ForwardedHeaders) isTrusted(ip net.IP) bool {
+	for _, ipNet := range f.trustedIPs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedNode returns the node of a peer in the Forwarded header, the IPv6
+// addresses being bracketed and quoted.
+func forwardedNode(ip net.IP, host string) string {
+	if ip == nil {
+		if len(host) == 0 {
+			return "unknown"
+		}
+		return quoteForwarded(host)
+	}
+	if ip.To4() == nil {
+		return "\"[" + ip.String() + "]\""
+	}
+	return ip.String()
+}
+
+// quoteForwarded quotes a value of the Forwarded header if it's not a token.
+func quoteForwarded(value string) string {
+	for _, c := range value {
+		if !isTokenChar(c) {
+			return "\"" + strings.Replace(strings.Replace(value, "\\", "\\\\", -1), "\"", "\\\"", -1) + "\""
+		}
+	}
+	return value
+}
+
+func isTokenChar(c rune) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", c)
+}
@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardedHeaders(t *testing.T) {
+	cases := []struct {
+		desc              string
+		trustedIPs        []string
+		forwarded         bool
+		remoteAddr        string
+		tls               bool
+		headers           map[string]string
+		expectedFor       string
+		expectedForwarded string
+	}{
+		{
+			desc:        "untrusted peer",
+			trustedIPs:  []string{"10.0.0.0/8"},
+			remoteAddr:  "192.168.1.1:1234",
+			headers:     map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": "https", "X-Real-Ip": "1.2.3.4"},
+			expectedFor: "",
+		},
+		{
+			desc:        "trusted peer",
+			trustedIPs:  []string{"10.0.0.0/8"},
+			remoteAddr:  "10.1.2.3:1234",
+			headers:     map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			expectedFor: "1.2.3.4",
+		},
+		{
+			desc:        "no trusted IP",
+			remoteAddr:  "10.1.2.3:1234",
+			headers:     map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			expectedFor: "",
+		},
+		{
+			desc:              "Forwarded from untrusted peer",
+			trustedIPs:        []string{"10.0.0.0/8"},
+			forwarded:         true,
+			remoteAddr:        "192.168.1.1:1234",
+			headers:           map[string]string{"Forwarded": "for=1.2.3.4"},
+			expectedForwarded: "for=192.168.1.1;host=localhost;proto=http",
+		},
+		{
+			desc:              "Forwarded from trusted peer",
+			trustedIPs:        []string{"10.1.2.3"},
+			forwarded:         true,
+			remoteAddr:        "10.1.2.3:1234",
+			tls:               true,
+			headers:           map[string]string{"Forwarded": "for=1.2.3.4"},
+			expectedForwarded: "for=1.2.3.4, for=10.1.2.3;host=localhost;proto=https",
+		},
+		{
+			desc:              "Forwarded IPv6",
+			forwarded:         true,
+			remoteAddr:        "[2001:db8::1]:1234",
+			expectedForwarded: "for=\"[2001:db8::1]\";host=localhost;proto=http",
+		},
+	}
+
+	for _, c := range cases {
+		forwardedHeaders, err := NewForwardedHeaders(c.trustedIPs, c.forwarded)
+		assert.NoError(t, err, c.desc)
+		var received http.Header
+		n := negroni.New(forwardedHeaders)
+		n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			received = r.Header
+		}))
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		req.RemoteAddr = c.remoteAddr
+		if c.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		for name, value := range c.headers {
+			req.Header.Set(name, value)
+		}
+		n.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, c.expectedFor, received.Get("X-Forwarded-For"), c.desc)
+		assert.Equal(t, c.expectedForwarded, received.Get("Forwarded"), c.desc)
+		if len(c.expectedFor) == 0 {
+			assert.Empty(t, received.Get("X-Forwarded-Proto"), c.desc)
+			assert.Empty(t, received.Get("X-Real-Ip"), c.desc)
+		}
+	}
+}
+
+func TestNewForwardedHeadersErrors(t *testing.T) {
+	_, err := NewForwardedHeaders([]string{"foo"}, false)
+	assert.Error(t, err)
+	_, err = NewForwardedHeaders([]string{"10.0.0.0/33"}, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/types"
+)
+
+// Headers is a middleware setting or removing custom request and response
+// headers, and adding security headers to responses.
+type Headers struct {
+	headers *types.Headers
+}
+
+// NewHeaders returns a new Headers from a frontend headers configuration.
+func NewHeaders(headers *types.Headers) *Headers {
+	return &Headers{headers: headers}
+}
+
+func (h *Headers) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if len(h.headers.AllowedHosts) > 0 && !h.isAllowedHost(r.Host) {
+		log.Debugf("Host %s of request %s is not allowed", r.Host, r.URL)
+		http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	isSSL := isSSLRequest(r)
+	if h.headers.SSLRedirect && !isSSL {
+		host := r.Host
+		if len(h.headers.SSLHost) > 0 {
+			host = h.headers.SSLHost
+		}
+		http.Redirect(rw, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+		return
+	}
+
+	for name, value := range h.headers.CustomRequestHeaders {
+		if len(value) == 0 {
+			r.Header.Del(name)
+		} else {
+			r.Header.Set(name, value)
+		}
+	}
+	next.ServeHTTP(&headersResponseWriter{rw: rw, headers: h.headers, isSSL: isSSL}, r)
+}
+
+func (h *Headers) isAllowedHost(requestHost string) bool {
+	host, _, err := net.SplitHostPort(requestHost)
+	if err != nil {
+		host = requestHost
+	}
+	for _, allowedHost := range h.headers.AllowedHosts {
+		if strings.EqualFold(host, allowedHost) || strings.EqualFold(requestHost, allowedHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSSLRequest reports whether the request was received over TLS, by
+// traefik or by a TLS terminating proxy in front of it.
+func isSSLRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// headersResponseWriter modifies the response headers right before they are
+// written, so that headers set by the backend can be overridden or removed.
+type headersResponseWriter struct {
+	rw          http.ResponseWriter
+	headers     *types.Headers
+	isSSL       bool
+	wroteHeader bool
+}
+
+func (hrw *headersResponseWriter) Header() http.Header {
+	return hrw.rw.Header()
+}
+
+func (hrw *headersResponseWriter) Write(b []byte) (int, error) {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	return hrw.rw.Write(b)
+}
+
+func (hrw *headersResponseWriter) WriteHeader(code int) {
+	if !hrw.wroteHeader {
+		hrw.wroteHeader = true
+		hrw.modifyHeaders()
+	}
+	hrw.rw.WriteHeader(code)
+}
+
+func (hrw *headersResponseWriter) modifyHeaders() {
+	header := hrw.rw.Header()
+	if hrw.headers.STSSeconds > 0 && hrw.isSSL {
+		sts := fmt.Sprintf("max-age=%d", hrw.headers.STSSeconds)
+		if hrw.headers.STSIncludeSubdomains {
+			sts += "; includeSubdomains"
+		}
+		if hrw.headers.STSPreload {
+			sts += "; preload"
+		}
+		header.Set("Strict-Transport-Security", sts)
+	}
+	if len(hrw.headers.CustomFrameOptionsValue) > 0 {
+		header.Set("X-Frame-Options", hrw.headers.CustomFrameOptionsValue)
+	} else if hrw.headers.FrameDeny {
+		header.Set("X-Frame-Options", "DENY")
+	}
+	if hrw.headers.ContentTypeNosniff {
+		header.Set("X-Content-Type-Options", "nosniff")
+	}
+	if hrw.headers.BrowserXSSFilter {
+		header.Set("X-XSS-Protection", "1; mode=block")
+	}
+	if len(hrw.headers.ContentSecurityPolicy) > 0 {
+		header.Set("Content-Security-Policy", hrw.headers.ContentSecurityPolicy)
+	}
+	for name, value := range hrw.headers.CustomResponseHeaders {
+		if len(value) == 0 {
+			header.Del(name)
+		} else {
+			header.Set(name, value)
+		}
+	}
+}
+
+func (hrw *headersResponseWriter) Flush() {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := hrw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (hrw *headersResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hrw.rw.(http.Hijacker).Hijack()
+}
+
+func (hrw *headersResponseWriter) CloseNotify() <-chan bool {
+	return closeNotify(hrw.rw)
+}
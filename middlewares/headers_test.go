@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHeadersHandler(headers *types.Headers) http.Handler {
+	n := negroni.New(NewHeaders(headers))
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Server", "backend")
+		rw.Header().Set("X-Request-Proto", r.Header.Get("X-Forwarded-Proto"))
+		rw.Header().Set("X-Request-Debug", r.Header.Get("X-Debug"))
+		rw.Write([]byte("traefik"))
+	}))
+	return n
+}
+
+func TestHeadersCustom(t *testing.T) {
+	handler := newHeadersHandler(&types.Headers{
+		CustomRequestHeaders:  map[string]string{"X-Forwarded-Proto": "https", "X-Debug": ""},
+		CustomResponseHeaders: map[string]string{"Server": "", "X-Custom": "value"},
+	})
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	req.Header.Set("X-Debug", "1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "https", recorder.Header().Get("X-Request-Proto"))
+	assert.Empty(t, recorder.Header().Get("X-Request-Debug"))
+	assert.Empty(t, recorder.Header().Get("Server"))
+	assert.Equal(t, "value", recorder.Header().Get("X-Custom"))
+	assert.Equal(t, "traefik", recorder.Body.String())
+}
+
+func TestHeadersSecurity(t *testing.T) {
+	handler := newHeadersHandler(&types.Headers{
+		STSSeconds:            315360000,
+		STSIncludeSubdomains:  true,
+		STSPreload:            true,
+		FrameDeny:             true,
+		ContentTypeNosniff:    true,
+		BrowserXSSFilter:      true,
+		ContentSecurityPolicy: "default-src 'self'",
+	})
+	req, _ := http.NewRequest("GET", "https://localhost/", nil)
+	req.TLS = &tls.ConnectionState{}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "max-age=315360000; includeSubdomains; preload", recorder.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "1; mode=block", recorder.Header().Get("X-XSS-Protection"))
+	assert.Equal(t, "default-src 'self'", recorder.Header().Get("Content-Security-Policy"))
+
+	// HSTS is only sent over HTTPS
+	req, _ = http.NewRequest("GET", "http://localhost/", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Empty(t, recorder.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+}
+
+func TestHeadersSSLRedirect(t *testing.T) {
+	handler := newHeadersHandler(&types.Headers{SSLRedirect: true, SSLHost: "secure.localhost"})
+	req, _ := http.NewRequest("GET", "http://localhost/foo?bar=1", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusMovedPermanently, recorder.Code)
+	assert.Equal(t, "https://secure.localhost/foo?bar=1", recorder.Header().Get("Location"))
+
+	req.Header.Set("X-Forwarded-Proto", "https")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestHeadersAllowedHosts(t *testing.T) {
+	handler := newHeadersHandler(&types.Headers{AllowedHosts: []string{"foo.localhost"}})
+	cases := []struct {
+		host     string
+		expected int
+	}{
+		{"foo.localhost", http.StatusOK},
+		{"foo.localhost:8080", http.StatusOK},
+		{"bar.localhost", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "http://"+c.host+"/", nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, c.expected, recorder.Code, c.host)
+	}
+}
+
+func TestHeadersCloseNotify(t *testing.T) {
+	n := negroni.New(NewHeaders(&types.Headers{}))
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-rw.(http.CloseNotifier).CloseNotify():
+			t.Error("unexpected close notification")
+		default:
+		}
+	}))
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	recorder := httptest.NewRecorder()
+	n.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
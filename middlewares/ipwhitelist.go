@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// IPWhitelister is a middleware rejecting requests whose client IP is not
+// in one of the whitelisted source ranges.
+type IPWhitelister struct {
+	whitelists         []*net.IPNet
+	xForwardedForDepth int
+}
+
+// NewIPWhitelister returns a new IPWhitelister from a list of CIDRs (a
+// single IP is accepted as a /32 or /128 range).
+// If xForwardedForDepth is greater than 0, the client IP is the
+// xForwardedForDepth-th address of the X-Forwarded-For header, starting from
+// the right (the one added by the closest trusted proxy is 1). Otherwise, the
+// client IP is the remote address of the connection.
+func NewIPWhitelister(sourceRanges []string, xForwardedForDepth int) (*IPWhitelister, error) {
+	if len(sourceRanges) == 0 {
+		return nil, fmt.Errorf("No whitelisted source range")
+	}
+	if xForwardedForDepth < 0 {
+		return nil, fmt.Errorf("Invalid X-Forwarded-For depth %d", xForwardedForDepth)
+	}
+	whitelister := &IPWhitelister{xForwardedForDepth: xForwardedForDepth}
+	for _, sourceRange := range sourceRanges {
+		whitelist, err := parseIPRange(sourceRange)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid whitelisted source range %s: %v", sourceRange, err)
+		}
+		whitelister.whitelists = append(whitelister.whitelists, whitelist)
+	}
+	return whitelister, nil
+}
+
+// parseIPRange parses a CIDR, or a single IP as a /32 or /128 range.
+func parseIPRange(ipRange string) (*net.IPNet, error) {
+	ipRange = strings.TrimSpace(ipRange)
+	if !strings.Contains(ipRange, "/") {
+		if ip := net.ParseIP(ipRange); ip != nil && ip.To4() != nil {
+			ipRange += "/32"
+		} else {
+			ipRange += "/128"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(ipRange)
+	return ipNet, err
+}
+
+func (whitelister *IPWhitelister) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ip := whitelister.clientIP(r)
+	if ip != nil && whitelister.contains(ip) {
+		next.ServeHTTP(rw, r)
+		return
+	}
+	log.Debugf("Source IP %s of request %s is not whitelisted", ip, r.URL)
+	http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
+
+func (whitelister *IPWhitelister) contains(ip net.IP) bool {
+	for _, whitelist := range whitelister.whitelists {
+		if whitelist.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP to check, or nil if it can't be found.
+func (whitelister *IPWhitelister) clientIP(r *http.Request) net.IP {
+	if whitelister.xForwardedForDepth > 0 {
+		var forwardedFor []string
+		for _, header := range r.Header[http.CanonicalHeaderKey("X-Forwarded-For")] {
+			forwardedFor = append(forwardedFor, strings.Split(header, ",")...)
+		}
+		if len(forwardedFor) < whitelister.xForwardedForDepth {
+			return nil
+		}
+		return net.ParseIP(strings.TrimSpace(forwardedFor[len(forwardedFor)-whitelister.xForwardedForDepth]))
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
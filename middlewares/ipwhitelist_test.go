@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPWhitelister(t *testing.T) {
+	cases := []struct {
+		desc          string
+		sourceRanges  []string
+		depth         int
+		remoteAddr    string
+		forwardedFor  []string
+		expectedState int
+	}{
+		{"remote address in range", []string{"10.0.0.0/8"}, 0, "10.1.2.3:1234", nil, http.StatusOK},
+		{"remote address out of range", []string{"10.0.0.0/8"}, 0, "192.168.1.1:1234", nil, http.StatusForbidden},
+		{"single IP", []string{"192.168.1.1"}, 0, "192.168.1.1:1234", nil, http.StatusOK},
+		{"IPv6", []string{"2001:db8::/32"}, 0, "[2001:db8::1]:1234", nil, http.StatusOK},
+		{"X-Forwarded-For ignored", []string{"10.0.0.0/8"}, 0, "192.168.1.1:1234", []string{"10.1.2.3"}, http.StatusForbidden},
+		{"X-Forwarded-For depth 1", []string{"10.0.0.0/8"}, 1, "192.168.1.1:1234", []string{"1.2.3.4, 10.1.2.3"}, http.StatusOK},
+		{"X-Forwarded-For depth 2", []string{"10.0.0.0/8"}, 2, "192.168.1.1:1234", []string{"10.1.2.3", "1.2.3.4"}, http.StatusOK},
+		{"X-Forwarded-For spoofed", []string{"10.0.0.0/8"}, 1, "192.168.1.1:1234", []string{"10.1.2.3, 1.2.3.4"}, http.StatusForbidden},
+		{"X-Forwarded-For too short", []string{"10.0.0.0/8"}, 2, "10.1.2.3:1234", []string{"10.1.2.3"}, http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		whitelister, err := NewIPWhitelister(c.sourceRanges, c.depth)
+		assert.NoError(t, err, c.desc)
+		n := negroni.New(whitelister)
+		n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}))
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		req.RemoteAddr = c.remoteAddr
+		for _, forwardedFor := range c.forwardedFor {
+			req.Header.Add("X-Forwarded-For", forwardedFor)
+		}
+		recorder := httptest.NewRecorder()
+		n.ServeHTTP(recorder, req)
+		assert.Equal(t, c.expectedState, recorder.Code, c.desc)
+	}
+}
+
+func TestNewIPWhitelisterErrors(t *testing.T) {
+	_, err := NewIPWhitelister(nil, 0)
+	assert.Error(t, err)
+	_, err = NewIPWhitelister([]string{"10.0.0.0/33"}, 0)
+	assert.Error(t, err)
+	_, err = NewIPWhitelister([]string{"foo"}, 0)
+	assert.Error(t, err)
+	_, err = NewIPWhitelister([]string{"10.0.0.0/8"}, -1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+// LeastConn is a load-balancer forwarding each request to the server with
+// the fewest requests in progress. Servers weights are ignored.
+type LeastConn struct {
+	next    http.Handler
+	mutex   sync.Mutex
+	servers []*leastConnServer
+}
+
+type leastConnServer struct {
+	url      *url.URL
+	inFlight int64
+}
+
+// NewLeastConn returns a new LeastConn forwarding requests to next, with the
+// URL of the chosen server.
+func NewLeastConn(next http.Handler) *LeastConn {
+	return &LeastConn{next: next}
+}
+
+func (lc *LeastConn) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	server := lc.acquire()
+	if server == nil {
+		log.Errorf("No server available for request %v", r.URL)
+		http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	defer lc.release(server)
+	newReq := *r
+	newReq.URL = server.url
+	lc.next.ServeHTTP(rw, &newReq)
+}
+
+// acquire returns the server with the fewest requests in progress, the first
+// added one on equality, and counts a new request on it.
+func (lc *LeastConn) acquire() *leastConnServer {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	var chosen *leastConnServer
+	for _, server := range lc.servers {
+		if chosen == nil || server.inFlight < chosen.inFlight {
+			chosen = server
+		}
+	}
+	if chosen != nil {
+		chosen.inFlight++
+	}
+	return chosen
+}
+
+func (lc *LeastConn) release(server *leastConnServer) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	server.inFlight--
+}
+
+// Servers returns the URLs of the servers.
+func (lc *LeastConn) Servers() []*url.URL {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	urls := make([]*url.URL, len(lc.servers))
+	for i, server := range lc.servers {
+		urls[i] = server.url
+	}
+	return urls
+}
+
+// UpsertServer adds a server, if not already there. Options are ignored.
+func (lc *LeastConn) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	if u == nil {
+		return errors.New("server URL can't be nil")
+	}
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	for _, server := range lc.servers {
+		if sameURL(server.url, u) {
+			return nil
+		}
+	}
+	lc.servers = append(lc.servers, &leastConnServer{url: copyURL(u)})
+	return nil
+}
+
+// RemoveServer removes a server. Requests in progress on it are not affected.
+func (lc *LeastConn) RemoveServer(u *url.URL) error {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	for i, server := range lc.servers {
+		if sameURL(server.url, u) {
+			lc.servers = append(lc.servers[:i], lc.servers[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("server not found")
+}
+
+func sameURL(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && a.Host == b.Host && a.Path == b.Path
+}
+
+func copyURL(u *url.URL) *url.URL {
+	copied := *u
+	return &copied
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeastConn(t *testing.T) {
+	release := make(chan bool)
+	started := make(chan bool)
+	var mutex sync.Mutex
+	hosts := []string{}
+	leastConn := NewLeastConn(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mutex.Unlock()
+		if r.Header.Get("X-Slow") == "true" {
+			started <- true
+			<-release
+		}
+	}))
+	server1, _ := url.Parse("http://10.0.0.1:80")
+	server2, _ := url.Parse("http://10.0.0.2:80")
+	assert.NoError(t, leastConn.UpsertServer(server1))
+	assert.NoError(t, leastConn.UpsertServer(server2))
+	assert.NoError(t, leastConn.UpsertServer(server2))
+	assert.Len(t, leastConn.Servers(), 2)
+
+	// a slow request keeps server1 busy, so the next ones go to server2
+	slowReq, _ := http.NewRequest("GET", "http://localhost/", nil)
+	slowReq.Header.Set("X-Slow", "true")
+	done := make(chan bool)
+	go func() {
+		leastConn.ServeHTTP(httptest.NewRecorder(), slowReq)
+		done <- true
+	}()
+	<-started
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		leastConn.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	release <- true
+	<-done
+	assert.Equal(t, []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.2:80", "10.0.0.2:80"}, hosts)
+
+	assert.NoError(t, leastConn.RemoveServer(server1))
+	assert.NoError(t, leastConn.RemoveServer(server2))
+	assert.Error(t, leastConn.RemoveServer(server2))
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	leastConn.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
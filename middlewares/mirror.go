@@ -0,0 +1,129 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/safe"
+	"github.com/vulcand/oxy/utils"
+)
+
+// defaultMirrorMaxBodySize is the default maximum size of the request bodies
+// buffered to be mirrored.
+const defaultMirrorMaxBodySize = 1024 * 1024
+
+// mirrorMaxInFlight is the maximum number of mirrored requests of a frontend
+// served at the same time, further requests not being mirrored, so that a
+// slow mirror backend can't pile up goroutines.
+const mirrorMaxInFlight = 100
+
+// Mirror is a middleware copying a percentage of the requests to a secondary
+// handler, whose responses are discarded. The mirrored requests are served in
+// the background, so that the response to the client does not wait for them.
+// Requests with a body bigger than maxBodySize are not mirrored.
+type Mirror struct {
+	handler     http.Handler
+	percent     int
+	maxBodySize int64
+	inFlight    chan struct{}
+}
+
+// NewMirror returns a new Mirror sending percent of the requests to handler.
+// A percent of 0 mirrors no request.
+func NewMirror(handler http.Handler, percent int, maxBodySize int64) *Mirror {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMirrorMaxBodySize
+	}
+	return &Mirror{
+		handler:     handler,
+		percent:     percent,
+		maxBodySize: maxBodySize,
+		inFlight:    make(chan struct{}, mirrorMaxInFlight),
+	}
+}
+
+func (m *Mirror) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// upgraded connections can't be mirrored
+	if len(r.Header.Get("Upgrade")) > 0 || rand.Intn(100) >= m.percent {
+		next(rw, r)
+		return
+	}
+	select {
+	case m.inFlight <- struct{}{}:
+	default:
+		log.Debugf("Too many mirrored requests in flight, not mirroring request %v", r.URL)
+		next(rw, r)
+		return
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, m.maxBodySize+1))
+		if err != nil {
+			<-m.inFlight
+			log.Errorf("Error reading body of request %v: %v", r.URL, err)
+			http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > m.maxBodySize {
+			<-m.inFlight
+			log.Debugf("Body of request %v is too big to be mirrored", r.URL)
+			r.Body = &readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			next(rw, r)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	mirrorReq := m.copyRequest(r, body)
+	safe.Go(func() {
+		defer func() { <-m.inFlight }()
+		m.handler.ServeHTTP(newDiscardResponseWriter(), mirrorReq)
+	})
+	next(rw, r)
+}
+
+// copyRequest returns a copy of the request with its own headers and body,
+// which is not tracked by the access logs.
+func (m *Mirror) copyRequest(r *http.Request, body []byte) *http.Request {
+	mirrorReq := *r
+	url := *r.URL
+	mirrorReq.URL = &url
+	mirrorReq.Header = make(http.Header)
+	utils.CopyHeaders(mirrorReq.Header, r.Header)
+	mirrorReq.Header.Del(loggerReqidHeader)
+	if r.Body != nil {
+		mirrorReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return &mirrorReq
+}
+
+// discardResponseWriter discards the response of a mirrored request.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func newDiscardResponseWriter() *discardResponseWriter {
+	return &discardResponseWriter{header: make(http.Header)}
+}
+
+func (drw *discardResponseWriter) Header() http.Header {
+	return drw.header
+}
+
+func (drw *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (drw *discardResponseWriter) WriteHeader(code int) {
+}
@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirror(t *testing.T) {
+	mirrored := make(chan string, 1)
+	mirror := NewMirror(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mirrored <- r.Header.Get("X-Test") + " " + string(body)
+		rw.Write([]byte("mirror response"))
+	}), 100, 0)
+
+	n := negroni.New(mirror)
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		rw.Write([]byte("primary response to " + string(body)))
+	}))
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("request body"))
+	req.Header.Set("X-Test", "mirrored")
+	recorder := httptest.NewRecorder()
+	n.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "primary response to request body", recorder.Body.String())
+	select {
+	case actual := <-mirrored:
+		assert.Equal(t, "mirrored request body", actual)
+	case <-time.After(time.Second):
+		t.Fatal("request was not mirrored")
+	}
+}
+
+func TestMirrorSkipped(t *testing.T) {
+	cases := []struct {
+		desc        string
+		percent     int
+		maxBodySize int64
+		header      string
+	}{
+		{"body too big", 100, 4, ""},
+		{"upgrade", 100, 0, "websocket"},
+		{"no percent", 0, 0, ""},
+	}
+
+	for _, c := range cases {
+		mirrored := false
+		mirror := NewMirror(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mirrored = true
+		}), c.percent, c.maxBodySize)
+		n := negroni.New(mirror)
+		n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			rw.Write(body)
+		}))
+		req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("request body"))
+		if len(c.header) > 0 {
+			req.Header.Set("Upgrade", c.header)
+		}
+		recorder := httptest.NewRecorder()
+		n.ServeHTTP(recorder, req)
+
+		assert.Equal(t, "request body", recorder.Body.String(), c.desc)
+		time.Sleep(10 * time.Millisecond)
+		assert.False(t, mirrored, c.desc)
+	}
+}
+
+func TestMirrorInFlight(t *testing.T) {
+	release := make(chan bool)
+	mirrored := make(chan bool, mirrorMaxInFlight+1)
+	mirror := NewMirror(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mirrored <- true
+		<-release
+	}), 100, 0)
+	n := negroni.New(mirror)
+	n.UseHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < mirrorMaxInFlight+1; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		n.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for i := 0; i < mirrorMaxInFlight; i++ {
+		<-mirrored
+	}
+	select {
+	case <-mirrored:
+		t.Fatal("request mirrored while too many mirrored requests were in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	for deadline := time.Now().Add(time.Second); len(mirror.inFlight) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("mirrored requests still in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	n.ServeHTTP(httptest.NewRecorder(), req)
+	select {
+	case <-mirrored:
+	case <-time.After(time.Second):
+		t.Fatal("request was not mirrored once the mirrored requests were done")
+	}
+}
@@ -0,0 +1,308 @@
+package middlewares
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+const (
+	defaultOutlierConsecutiveErrors = 5
+	defaultOutlierBaseEjectionTime  = 30 * time.Second
+	defaultOutlierMaxEjectionTime   = 300 * time.Second
+)
+
+// OutlierLoadBalancer includes the load-balancer operations used to eject
+// and put back servers.
+type OutlierLoadBalancer interface {
+	RemoveServer(u *url.URL) error
+	UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error
+	Servers() []*url.URL
+}
+
+// OutlierHealthCheck includes the health check operations used to avoid
+// putting back a server held by the active health check.
+type OutlierHealthCheck interface {
+	Disabled(u *url.URL) bool
+}
+
+// OutlierDetector is a passive health check: it watches the responses of
+// each server of a backend, and ejects from the load-balancer a server
+// returning too many consecutive 5xx or connection errors. The server is put
+// back after a cooldown, doubled at each new ejection up to a maximum.
+// The last server of a backend is never ejected.
+type OutlierDetector struct {
+	next              http.Handler
+	consecutiveErrors int
+	baseEjectionTime  time.Duration
+	maxEjectionTime   time.Duration
+	weights           map[string]int
+	lb                OutlierLoadBalancer
+	healthCheck       OutlierHealthCheck
+	mutex             sync.Mutex
+	servers           map[string]*outlierServer
+	stopped           bool
+}
+
+type outlierServer struct {
+	url               *url.URL
+	consecutiveErrors int
+	ejections         int
+	ejected           bool
+	ejectedUntil      time.Time
+	putBackAt         time.Time
+}
+
+// OutlierServerState is the ejection state of a server.
+type OutlierServerState struct {
+	ConsecutiveErrors int        `json:"consecutiveErrors"`
+	Ejections         int        `json:"ejections"`
+	Ejected           bool       `json:"ejected"`
+	EjectedUntil      *time.Time `json:"ejectedUntil,omitempty"`
+}
+
+// NewOutlierDetector returns a new OutlierDetector forwarding requests to
+// next. A server is ejected after consecutiveErrors errors, for
+// baseEjectionTime the first time, and up to maxEjectionTime. Zero values
+// use the defaults. Weights are the configured weights of the servers,
+// indexed by URL, used when putting them back: a server missing from them is
+// not put back.
+func NewOutlierDetector(next http.Handler, consecutiveErrors int, baseEjectionTime, maxEjectionTime time.Duration, weights map[string]int) *OutlierDetector {
+	if consecutiveErrors <= 0 {
+		consecutiveErrors = defaultOutlierConsecutiveErrors
+	}
+	if baseEjectionTime <= 0 {
+		baseEjectionTime = defaultOutlierBaseEjectionTime
+	}
+	if maxEjectionTime <= 0 {
+		maxEjectionTime = defaultOutlierMaxEjectionTime
+	}
+	if maxEjectionTime < baseEjectionTime {
+		maxEjectionTime = baseEjectionTime
+	}
+	return &OutlierDetector{
+		next:              next,
+		consecutiveErrors: consecutiveErrors,
+		baseEjectionTime:  baseEjectionTime,
+		maxEjectionTime:   maxEjectionTime,
+		weights:           weights,
+		servers:           make(map[string]*outlierServer),
+	}
+}
+
+// SetLoadBalancer sets the load-balancer the servers are ejected from. It
+// must be set before serving requests, since the load-balancer forwards its
+// requests to the detector. The servers still ejected in the state restored
+// from a previous detector are removed from it until their ejection ends.
+func (od *OutlierDetector) SetLoadBalancer(lb OutlierLoadBalancer) {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	od.lb = lb
+	now := time.Now()
+	for _, server := range od.servers {
+		if !server.ejected {
+			continue
+		}
+		remaining := server.ejectedUntil.Sub(now)
+		if remaining <= 0 || len(lb.Servers()) <= 1 {
+			server.ejected = false
+			server.consecutiveErrors = 0
+			server.putBackAt = now
+			continue
+		}
+		if err := lb.RemoveServer(server.url); err != nil {
+			log.Errorf("Error ejecting server %s: %v", server.url, err)
+			server.ejected = false
+			continue
+		}
+		ejectedServer := server
+		time.AfterFunc(remaining, func() { od.putBack(ejectedServer) })
+	}
+}
+
+// SetHealthCheck sets the active health check of the backend, if any.
+func (od *OutlierDetector) SetHealthCheck(healthCheck OutlierHealthCheck) {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	od.healthCheck = healthCheck
+}
+
+// Restore carries over the state of the configured servers from previous,
+// the detector of the backend in the running configuration, so that a reload
+// neither puts back ejected servers early nor resets their ejection time. It
+// must be called before SetLoadBalancer.
+func (od *OutlierDetector) Restore(previous *OutlierDetector) {
+	previous.mutex.Lock()
+	defer previous.mutex.Unlock()
+	for key, server := range previous.servers {
+		if _, configured := od.weights[key]; !configured {
+			continue
+		}
+		restored := *server
+		od.servers[key] = &restored
+	}
+}
+
+// Stop stops putting back the ejected servers, once the detector is replaced
+// by a reload.
+func (od *OutlierDetector) Stop() {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	od.stopped = true
+}
+
+func (od *OutlierDetector) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	orw := &outlierResponseWriter{rw: rw, code: http.StatusOK}
+	od.next.ServeHTTP(orw, r)
+	od.record(r.URL, orw.code >= http.StatusInternalServerError)
+}
+
+// record counts the result of a request to a server, and ejects it if needed.
+func (od *OutlierDetector) record(serverURL *url.URL, failed bool) {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	server := od.server(serverURL)
+	if !failed {
+		server.consecutiveErrors = 0
+		return
+	}
+	server.consecutiveErrors++
+	if server.ejected || server.consecutiveErrors < od.consecutiveErrors || od.lb == nil {
+		return
+	}
+	if len(od.lb.Servers()) <= 1 {
+		log.Warnf("Outlier detection: not ejecting %s, last server of its backend", server.url)
+		return
+	}
+	if err := od.lb.RemoveServer(server.url); err != nil {
+		log.Errorf("Error ejecting server %s: %v", server.url, err)
+		return
+	}
+	now := time.Now()
+	// A server staying in the load-balancer longer than the maximum ejection
+	// time is considered recovered.
+	if !server.putBackAt.IsZero() && now.Sub(server.putBackAt) > od.maxEjectionTime {
+		server.ejections = 0
+	}
+	server.ejections++
+	ejectionTime := od.ejectionTime(server.ejections)
+	server.ejected = true
+	server.ejectedUntil = now.Add(ejectionTime)
+	log.Warnf("Outlier detection: ejecting server %s for %s after %d consecutive errors", server.url, ejectionTime, server.consecutiveErrors)
+	time.AfterFunc(ejectionTime, func() { od.putBack(server) })
+}
+
+// putBack ends the ejection of a server, putting it back in the load-balancer
+// unless it is no longer configured or the health check holds it: the health
+// check puts it back itself once it recovers.
+func (od *OutlierDetector) putBack(server *outlierServer) {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	if od.stopped {
+		return
+	}
+	weight, configured := od.weights[server.url.String()]
+	switch {
+	case !configured:
+		log.Debugf("Outlier detection: not putting back server %s, no longer configured", server.url)
+	case od.healthCheck != nil && od.healthCheck.Disabled(server.url):
+		log.Debugf("Outlier detection: not putting back server %s, held by the health check", server.url)
+	default:
+		log.Infof("Outlier detection: putting back server %s", server.url)
+		if err := od.lb.UpsertServer(server.url, roundrobin.Weight(weight)); err != nil {
+			log.Errorf("Error putting back server %s: %v", server.url, err)
+		}
+	}
+	server.ejected = false
+	server.consecutiveErrors = 0
+	server.putBackAt = time.Now()
+}
+
+// ejectionTime returns the base ejection time doubled for each previous
+// ejection, up to the maximum ejection time.
+func (od *OutlierDetector) ejectionTime(ejections int) time.Duration {
+	ejectionTime := od.baseEjectionTime
+	for i := 1; i < ejections && ejectionTime < od.maxEjectionTime; i++ {
+		ejectionTime *= 2
+	}
+	if ejectionTime > od.maxEjectionTime {
+		return od.maxEjectionTime
+	}
+	return ejectionTime
+}
+
+func (od *OutlierDetector) server(serverURL *url.URL) *outlierServer {
+	key := serverURL.String()
+	server, ok := od.servers[key]
+	if !ok {
+		server = &outlierServer{url: copyURL(serverURL)}
+		od.servers[key] = server
+	}
+	return server
+}
+
+// State returns the ejection state of the servers which received requests,
+// indexed by URL.
+func (od *OutlierDetector) State() map[string]OutlierServerState {
+	od.mutex.Lock()
+	defer od.mutex.Unlock()
+	state := make(map[string]OutlierServerState)
+	for key, server := range od.servers {
+		serverState := OutlierServerState{
+			ConsecutiveErrors: server.consecutiveErrors,
+			Ejections:         server.ejections,
+			Ejected:           server.ejected,
+		}
+		if server.ejected {
+			ejectedUntil := server.ejectedUntil
+			serverState.EjectedUntil = &ejectedUntil
+		}
+		state[key] = serverState
+	}
+	return state
+}
+
+// outlierResponseWriter keeps the status code of the response.
+type outlierResponseWriter struct {
+	rw          http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (orw *outlierResponseWriter) Header() http.Header {
+	return orw.rw.Header()
+}
+
+func (orw *outlierResponseWriter) WriteHeader(code int) {
+	if !orw.wroteHeader {
+		orw.wroteHeader = true
+		orw.code = code
+	}
+	orw.rw.WriteHeader(code)
+}
+
+func (orw *outlierResponseWriter) Write(b []byte) (int, error) {
+	orw.wroteHeader = true
+	return orw.rw.Write(b)
+}
+
+func (orw *outlierResponseWriter) Flush() {
+	orw.wroteHeader = true
+	if f, ok := orw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (orw *outlierResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return orw.rw.(http.Hijacker).Hijack()
+}
+
+func (orw *outlierResponseWriter) CloseNotify() <-chan bool {
+	return closeNotify(orw.rw)
+}
@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutlierDetector(t *testing.T) {
+	failing, _ := url.Parse("http://10.0.0.1:80")
+	healthy, _ := url.Parse("http://10.0.0.2:80")
+	detector := NewOutlierDetector(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == failing.Host {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}), 2, 50*time.Millisecond, time.Second, map[string]int{failing.String(): 1, healthy.String(): 1})
+	lb := NewLeastConn(detector)
+	detector.SetLoadBalancer(lb)
+	lb.UpsertServer(failing)
+	lb.UpsertServer(healthy)
+
+	// The least-connections load-balancer picks the first server when idle.
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		lb.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Len(t, lb.Servers(), 1)
+	assert.Equal(t, healthy.String(), lb.Servers()[0].String())
+	state := detector.State()[failing.String()]
+	assert.True(t, state.Ejected)
+	assert.Equal(t, 1, state.Ejections)
+	assert.NotNil(t, state.EjectedUntil)
+
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	recorder := httptest.NewRecorder()
+	lb.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, lb.Servers(), 2)
+	state = detector.State()[failing.String()]
+	assert.False(t, state.Ejected)
+	assert.Equal(t, 0, state.ConsecutiveErrors)
+	assert.Nil(t, state.EjectedUntil)
+}
+
+func TestOutlierDetectorLastServer(t *testing.T) {
+	server, _ := url.Parse("http://10.0.0.1:80")
+	detector := NewOutlierDetector(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}), 1, 0, 0, nil)
+	lb := NewLeastConn(detector)
+	detector.SetLoadBalancer(lb)
+	lb.UpsertServer(server)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		lb.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Len(t, lb.Servers(), 1)
+	assert.False(t, detector.State()[server.String()].Ejected)
+	assert.Equal(t, 3, detector.State()[server.String()].ConsecutiveErrors)
+}
+
+func TestOutlierDetectorEjectionTime(t *testing.T) {
+	detector := NewOutlierDetector(nil, 0, 10*time.Second, 35*time.Second, nil)
+	assert.Equal(t, 10*time.Second, detector.ejectionTime(1))
+	assert.Equal(t, 20*time.Second, detector.ejectionTime(2))
+	assert.Equal(t, 35*time.Second, detector.ejectionTime(3))
+	assert.Equal(t, 35*time.Second, detector.ejectionTime(10))
+}
+
+type testOutlierHealthCheck struct {
+	disabled map[string]bool
+}
+
+func (hc *testOutlierHealthCheck) Disabled(u *url.URL) bool {
+	return hc.disabled[u.String()]
+}
+
+func TestOutlierDetectorPutBack(t *testing.T) {
+	removed, _ := url.Parse("http://10.0.0.1:80")
+	held, _ := url.Parse("http://10.0.0.2:80")
+	ejected, _ := url.Parse("http://10.0.0.3:80")
+	healthy, _ := url.Parse("http://10.0.0.4:80")
+	detector := NewOutlierDetector(nil, 0, 0, 0, map[string]int{held.String(): 1, ejected.String(): 3, healthy.String(): 1})
+	lb := NewLeastConn(detector)
+	lb.UpsertServer(healthy)
+	detector.SetLoadBalancer(lb)
+	detector.SetHealthCheck(&testOutlierHealthCheck{disabled: map[string]bool{held.String(): true}})
+
+	for _, u := range []*url.URL{removed, held, ejected} {
+		server := detector.server(u)
+		server.ejected = true
+		detector.putBack(server)
+		assert.False(t, detector.State()[u.String()].Ejected)
+	}
+	servers := lb.Servers()
+	assert.Len(t, servers, 2)
+	assert.Equal(t, ejected.String(), servers[1].String())
+
+	detector.Stop()
+	lb.RemoveServer(ejected)
+	detector.putBack(detector.server(ejected))
+	assert.Len(t, lb.Servers(), 1)
+}
+
+func TestOutlierDetectorRestore(t *testing.T) {
+	ejected, _ := url.Parse("http://10.0.0.1:80")
+	expired, _ := url.Parse("http://10.0.0.2:80")
+	removed, _ := url.Parse("http://10.0.0.3:80")
+	healthy, _ := url.Parse("http://10.0.0.4:80")
+	previous := NewOutlierDetector(nil, 0, 0, 0, nil)
+	now := time.Now()
+	for _, u := range []*url.URL{ejected, expired, removed} {
+		server := previous.server(u)
+		server.ejected = true
+		server.ejections = 2
+		server.ejectedUntil = now.Add(50 * time.Millisecond)
+	}
+	previous.server(expired).ejectedUntil = now.Add(-time.Second)
+
+	detector := NewOutlierDetector(nil, 0, 0, 0, map[string]int{ejected.String(): 1, expired.String(): 1, healthy.String(): 1})
+	detector.Restore(previous)
+	lb := NewLeastConn(detector)
+	for _, u := range []*url.URL{ejected, expired, healthy} {
+		lb.UpsertServer(u)
+	}
+	detector.SetLoadBalancer(lb)
+
+	state := detector.State()
+	assert.Len(t, state, 2)
+	assert.True(t, state[ejected.String()].Ejected)
+	assert.Equal(t, 2, state[ejected.String()].Ejections)
+	assert.False(t, state[expired.String()].Ejected)
+	assert.Equal(t, 2, state[expired.String()].Ejections)
+	assert.Len(t, lb.Servers(), 2)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, lb.Servers(), 3)
+	assert.False(t, detector.State()[ejected.String()].Ejected)
+}
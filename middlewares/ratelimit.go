@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vulcand/oxy/ratelimit"
+)
+
+// RateLimitErrorHandler answers rate limited requests with a 429 status code
+// and a Retry-After header, instead of the X-Retry-In header set by oxy.
+type RateLimitErrorHandler struct {
+	rateErrHandler *ratelimit.RateErrHandler
+}
+
+// NewRateLimitErrorHandler returns a new RateLimitErrorHandler.
+func NewRateLimitErrorHandler() *RateLimitErrorHandler {
+	return &RateLimitErrorHandler{rateErrHandler: &ratelimit.RateErrHandler{}}
+}
+
+func (handler *RateLimitErrorHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request, err error) {
+	handler.rateErrHandler.ServeHTTP(&retryAfterWriter{ResponseWriter: rw}, r, err)
+}
+
+type retryAfterWriter struct {
+	http.ResponseWriter
+}
+
+func (writer *retryAfterWriter) WriteHeader(code int) {
+	if retryIn := writer.Header().Get("X-Retry-In"); len(retryIn) > 0 {
+		writer.Header().Del("X-Retry-In")
+		if delay, err := time.ParseDuration(retryIn); err == nil {
+			// Retry-After is a number of seconds, round up to not retry too soon
+			seconds := int(math.Ceil(delay.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			writer.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+	}
+	writer.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulcand/oxy/ratelimit"
+	"github.com/vulcand/oxy/utils"
+)
+
+func TestRateLimitErrorHandler(t *testing.T) {
+	rateSet := ratelimit.NewRateSet()
+	assert.NoError(t, rateSet.Add(10*time.Second, 1, 1))
+	extractor, err := utils.NewExtractor("client.ip")
+	assert.NoError(t, err)
+	limiter, err := ratelimit.New(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), extractor, rateSet, ratelimit.ErrorHandler(NewRateLimitErrorHandler()))
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	recorder := httptest.NewRecorder()
+	limiter.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	limiter.ServeHTTP(recorder, req)
+	assert.Equal(t, 429, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("X-Retry-In"))
+	assert.Contains(t, []string{"9", "10"}, recorder.Header().Get("Retry-After"))
+
+	req.RemoteAddr = "10.0.0.2:1234"
+	recorder = httptest.NewRecorder()
+	limiter.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -3,40 +3,75 @@ package middlewares
 import (
 	"bufio"
 	"bytes"
-	log "github.com/Sirupsen/logrus"
-	"github.com/vulcand/oxy/utils"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/vulcand/oxy/utils"
 )
 
-// Retry is a middleware that retries requests
+// defaultRetryMaxRequestBodyBytes is the default maximum size of the request
+// bodies buffered to be replayed.
+const defaultRetryMaxRequestBodyBytes = 2 * 1024 * 1024
+
+// Retry is a middleware that retries requests.
+// A request is retried only if it failed with a network error before any
+// response byte was sent to the client, so responses are streamed as is.
+// Request bodies are buffered to be replayed, unless they are bigger than
+// maxRequestBodyBytes, in which case the request is not retried.
 type Retry struct {
-	attempts int
-	next     http.Handler
+	attempts            int
+	maxRequestBodyBytes int64
+	next                http.Handler
 }
 
 // NewRetry returns a new Retry instance
-func NewRetry(attempts int, next http.Handler) *Retry {
+func NewRetry(attempts int, maxRequestBodyBytes int64, next http.Handler) *Retry {
+	if maxRequestBodyBytes <= 0 {
+		maxRequestBodyBytes = defaultRetryMaxRequestBodyBytes
+	}
 	return &Retry{
-		attempts: attempts,
-		next:     next,
+		attempts:            attempts,
+		maxRequestBodyBytes: maxRequestBodyBytes,
+		next:                next,
 	}
 }
 
 func (retry *Retry) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	attempts := 1
-	for {
-		recorder := NewRecorder()
-		recorder.responseWriter = rw
-		retry.next.ServeHTTP(recorder, r)
-		if !isNetworkError(recorder.Code) || attempts >= retry.attempts {
-			utils.CopyHeaders(rw.Header(), recorder.Header())
-			rw.WriteHeader(recorder.Code)
-			rw.Write(recorder.Body.Bytes())
-			break
+	var body []byte
+	attempts := retry.attempts
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, retry.maxRequestBodyBytes+1))
+		if err != nil {
+			log.Errorf("Error reading body of request %v: %v", r.URL, err)
+			http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > retry.maxRequestBodyBytes {
+			log.Debugf("Body of request %v is too big to be replayed, disabling retries", r.URL)
+			r.Body = &readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			retry.next.ServeHTTP(rw, r)
+			return
 		}
-		attempts++
-		log.Debugf("New attempt %d for request: %v", attempts, r.URL)
+	}
+
+	for attempt := 1; ; attempt++ {
+		if r.Body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		retryRw := &retryResponseWriter{
+			rw:          rw,
+			header:      make(http.Header),
+			lastAttempt: attempt >= attempts,
+		}
+		retry.next.ServeHTTP(retryRw, r)
+		if !retryRw.failed {
+			return
+		}
+		log.Debugf("New attempt %d for request: %v", attempt+1, r.URL)
 	}
 }
 
@@ -44,49 +79,70 @@ func isNetworkError(status int) bool {
 	return status == http.StatusBadGateway || status == http.StatusGatewayTimeout
 }
 
-// ResponseRecorder is an implementation of http.ResponseWriter that
-// records its mutations for later inspection in tests.
-type ResponseRecorder struct {
-	Code      int           // the HTTP response code from WriteHeader
-	HeaderMap http.Header   // the HTTP response headers
-	Body      *bytes.Buffer // if non-nil, the bytes.Buffer to append written data to
+// readCloser reads from a reader and closes a closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// retryResponseWriter writes the response to the client, unless it's a
+// network error that should be retried, in which case it's discarded.
+// Headers are kept apart until the response status is known, so that those
+// of a discarded response don't leak into the next attempt.
+type retryResponseWriter struct {
+	rw          http.ResponseWriter
+	header      http.Header
+	lastAttempt bool
+	wroteHeader bool
+	failed      bool
+}
 
-	responseWriter http.ResponseWriter
+func (rrw *retryResponseWriter) Header() http.Header {
+	if rrw.wroteHeader && !rrw.failed {
+		return rrw.rw.Header()
+	}
+	return rrw.header
 }
 
-// NewRecorder returns an initialized ResponseRecorder.
-func NewRecorder() *ResponseRecorder {
-	return &ResponseRecorder{
-		HeaderMap: make(http.Header),
-		Body:      new(bytes.Buffer),
-		Code:      200,
+func (rrw *retryResponseWriter) WriteHeader(code int) {
+	if rrw.wroteHeader {
+		return
+	}
+	rrw.wroteHeader = true
+	if isNetworkError(code) && !rrw.lastAttempt {
+		rrw.failed = true
+		return
 	}
+	utils.CopyHeaders(rrw.rw.Header(), rrw.header)
+	rrw.rw.WriteHeader(code)
 }
 
-// Header returns the response headers.
-func (rw *ResponseRecorder) Header() http.Header {
-	m := rw.HeaderMap
-	if m == nil {
-		m = make(http.Header)
-		rw.HeaderMap = m
+func (rrw *retryResponseWriter) Write(b []byte) (int, error) {
+	if !rrw.wroteHeader {
+		rrw.WriteHeader(http.StatusOK)
+	}
+	if rrw.failed {
+		return len(b), nil
 	}
-	return m
+	return rrw.rw.Write(b)
 }
 
-// Write always succeeds and writes to rw.Body, if not nil.
-func (rw *ResponseRecorder) Write(buf []byte) (int, error) {
-	if rw.Body != nil {
-		return rw.Body.Write(buf)
+func (rrw *retryResponseWriter) Flush() {
+	if !rrw.wroteHeader {
+		rrw.WriteHeader(http.StatusOK)
+	}
+	if rrw.failed {
+		return
+	}
+	if f, ok := rrw.rw.(http.Flusher); ok {
+		f.Flush()
 	}
-	return 0, nil
 }
 
-// WriteHeader sets rw.Code.
-func (rw *ResponseRecorder) WriteHeader(code int) {
-	rw.Code = code
+func (rrw *retryResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rrw.rw.(http.Hijacker).Hijack()
 }
 
-// Hijack hijacks the connection
-func (rw *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	return rw.responseWriter.(http.Hijacker).Hijack()
+func (rrw *retryResponseWriter) CloseNotify() <-chan bool {
+	return closeNotify(rrw.rw)
 }
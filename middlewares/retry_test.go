@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		desc                string
+		attempts            int
+		maxRequestBodyBytes int64
+		failures            int
+		expectedCalls       int
+		expectedCode        int
+	}{
+		{"success", 3, 0, 0, 1, http.StatusOK},
+		{"retried", 3, 0, 2, 3, http.StatusOK},
+		{"too many failures", 3, 0, 5, 3, http.StatusBadGateway},
+		{"body too big to be replayed", 3, 4, 5, 1, http.StatusBadGateway},
+	}
+
+	for _, c := range cases {
+		calls := 0
+		retry := NewRetry(c.attempts, c.maxRequestBodyBytes, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			calls++
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err, c.desc)
+			assert.Equal(t, "request body", string(body), c.desc)
+			if calls <= c.failures {
+				rw.Header().Set("X-Failed-Attempt", "true")
+				http.Error(rw, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+				return
+			}
+			rw.Write([]byte("response body"))
+		}))
+		req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("request body"))
+		recorder := httptest.NewRecorder()
+		retry.ServeHTTP(recorder, req)
+		assert.Equal(t, c.expectedCalls, calls, c.desc)
+		assert.Equal(t, c.expectedCode, recorder.Code, c.desc)
+		if c.expectedCode == http.StatusOK {
+			assert.Equal(t, "response body", recorder.Body.String(), c.desc)
+			assert.Empty(t, recorder.Header().Get("X-Failed-Attempt"), c.desc)
+		}
+	}
+}
+
+func TestRetryStreaming(t *testing.T) {
+	calls := 0
+	var flushedBody string
+	recorder := httptest.NewRecorder()
+	retry := NewRetry(3, 0, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		rw.Write([]byte("first chunk"))
+		rw.(http.Flusher).Flush()
+		flushedBody = recorder.Body.String()
+		rw.Write([]byte(", second chunk"))
+	}))
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	retry.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 1, calls)
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, "first chunk", flushedBody)
+	assert.Equal(t, "first chunk, second chunk", recorder.Body.String())
+}
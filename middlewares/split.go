@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"regexp"
+)
+
+const splitCookiePrefix = "_TRAEFIK_SPLIT_"
+
+var splitCookieInvalidChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+// SplitBackend is a backend of a Split, receiving a share of the requests
+// proportional to its weight.
+type SplitBackend struct {
+	Name    string
+	Weight  int
+	Handler http.Handler
+}
+
+// Split is a handler sending the requests of a frontend to several backends,
+// according to their weights. When sticky, the chosen backend is stored in a
+// cookie, so that the following requests of the client go to the same
+// backend as long as its weight is not 0.
+type Split struct {
+	backends    []SplitBackend
+	totalWeight int
+	sticky      bool
+	cookieName  string
+}
+
+// NewSplit returns a new Split for the given frontend.
+func NewSplit(frontendName string, backends []SplitBackend, sticky bool) (*Split, error) {
+	split := &Split{
+		sticky:     sticky,
+		cookieName: splitCookiePrefix + splitCookieInvalidChars.ReplaceAllString(frontendName, "_"),
+	}
+	for _, backend := range backends {
+		if backend.Weight < 0 {
+			return nil, errors.New("Negative weight for backend " + backend.Name)
+		}
+		split.backends = append(split.backends, backend)
+		split.totalWeight += backend.Weight
+	}
+	if split.totalWeight == 0 {
+		return nil, errors.New("The weight of at least one backend must be positive")
+	}
+	return split, nil
+}
+
+func (s *Split) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if s.sticky {
+		if cookie, err := r.Cookie(s.cookieName); err == nil {
+			if backend := s.find(cookie.Value); backend != nil {
+				backend.Handler.ServeHTTP(rw, r)
+				return
+			}
+		}
+	}
+	backend := s.choose()
+	if s.sticky {
+		http.SetCookie(rw, &http.Cookie{Name: s.cookieName, Value: backend.Name, Path: "/"})
+	}
+	backend.Handler.ServeHTTP(rw, r)
+}
+
+// find returns the backend with the given name, if it still receives requests.
+func (s *Split) find(name string) *SplitBackend {
+	for i := range s.backends {
+		if s.backends[i].Name == name && s.backends[i].Weight > 0 {
+			return &s.backends[i]
+		}
+	}
+	return nil
+}
+
+// choose picks a backend at random, according to the weights.
+func (s *Split) choose() *SplitBackend {
+	n := rand.Intn(s.totalWeight)
+	for i := range s.backends {
+		if n < s.backends[i].Weight {
+			return &s.backends[i]
+		}
+		n -= s.backends[i].Weight
+	}
+	return &s.backends[len(s.backends)-1]
+}
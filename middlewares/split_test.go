@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSplitTestBackend(name string, weight int) SplitBackend {
+	return SplitBackend{
+		Name:   name,
+		Weight: weight,
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Write([]byte(name))
+		}),
+	}
+}
+
+func TestSplitWeights(t *testing.T) {
+	split, err := NewSplit("frontend1", []SplitBackend{
+		newSplitTestBackend("stable", 9),
+		newSplitTestBackend("canary", 1),
+		newSplitTestBackend("disabled", 0),
+	}, false)
+	assert.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		split.ServeHTTP(recorder, req)
+		counts[recorder.Body.String()]++
+		assert.Empty(t, recorder.Header().Get("Set-Cookie"))
+	}
+	assert.Equal(t, 0, counts["disabled"])
+	assert.InDelta(t, 900, counts["stable"], 60)
+	assert.InDelta(t, 100, counts["canary"], 60)
+}
+
+func TestSplitSticky(t *testing.T) {
+	split, err := NewSplit("frontend-Host-foo.bar", []SplitBackend{
+		newSplitTestBackend("stable", 1),
+		newSplitTestBackend("canary", 1),
+		newSplitTestBackend("disabled", 0),
+	}, true)
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	split.ServeHTTP(recorder, req)
+	chosen := recorder.Body.String()
+	assert.Equal(t, "_TRAEFIK_SPLIT_frontend-Host-foo_bar="+chosen+"; Path=/", recorder.Header().Get("Set-Cookie"))
+
+	for i := 0; i < 10; i++ {
+		recorder = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "http://localhost/", nil)
+		req.AddCookie(&http.Cookie{Name: "_TRAEFIK_SPLIT_frontend-Host-foo_bar", Value: chosen})
+		split.ServeHTTP(recorder, req)
+		assert.Equal(t, chosen, recorder.Body.String())
+		assert.Empty(t, recorder.Header().Get("Set-Cookie"))
+	}
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "http://localhost/", nil)
+	req.AddCookie(&http.Cookie{Name: "_TRAEFIK_SPLIT_frontend-Host-foo_bar", Value: "disabled"})
+	split.ServeHTTP(recorder, req)
+	assert.NotEqual(t, "disabled", recorder.Body.String())
+	assert.NotEmpty(t, recorder.Header().Get("Set-Cookie"))
+}
+
+func TestSplitInvalidWeights(t *testing.T) {
+	_, err := NewSplit("frontend1", []SplitBackend{newSplitTestBackend("stable", 0)}, false)
+	assert.Error(t, err)
+	_, err = NewSplit("frontend1", []SplitBackend{newSplitTestBackend("stable", -1)}, false)
+	assert.Error(t, err)
+}
@@ -3,8 +3,16 @@ package middlewares
 import (
 	"net/http"
 	"strings"
+
+	"github.com/gorilla/context"
 )
 
+type requestURIKey int
+
+// originalRequestURIKey is the context key of the request URI of a request
+// before its prefix is stripped.
+const originalRequestURIKey requestURIKey = 0
+
 // StripPrefix is a middleware used to strip prefix from an URL request
 type StripPrefix struct {
 	Handler  http.Handler
@@ -14,6 +22,8 @@ type StripPrefix struct {
 func (s *StripPrefix) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	for _, prefix := range s.Prefixes {
 		if p := strings.TrimPrefix(r.URL.Path, strings.TrimSpace(prefix)); len(p) < len(r.URL.Path) {
+			context.Set(r, originalRequestURIKey, r.RequestURI)
+			defer context.Delete(r, originalRequestURIKey)
 			r.URL.Path = p
 			r.RequestURI = r.URL.RequestURI()
 			s.Handler.ServeHTTP(w, r)
@@ -27,3 +37,12 @@ func (s *StripPrefix) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (s *StripPrefix) SetHandler(Handler http.Handler) {
 	s.Handler = Handler
 }
+
+// originalRequestURI returns the request URI sent by the client, before a
+// StripPrefix rewrote it.
+func originalRequestURI(r *http.Request) string {
+	if requestURI, ok := context.Get(r, originalRequestURIKey).(string); ok {
+		return requestURI
+	}
+	return r.RequestURI
+}
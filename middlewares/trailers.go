@@ -0,0 +1,193 @@
+package middlewares
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// trailersIDHeader is the internal header linking a forwarded request to the
+// handler of the client request.
+const trailersIDHeader = "X-Traefik-Trailers-Id"
+
+// grpcTrailers are the trailers announced to the clients for gRPC responses,
+// whose servers send them without announcing them.
+var grpcTrailers = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+
+var trailersID uint64
+
+var trailersResponses = struct {
+	sync.Mutex
+	responses map[string]*http.Response
+}{responses: make(map[string]*http.Response)}
+
+// Trailers is a handler sending the response trailers of the servers to the
+// clients, which the forwarder doesn't do. It must wrap a forwarder whose
+// round tripper is a TrailersRoundTripper.
+// The trailers are announced in the response headers, as declared by the
+// server or the gRPC ones for gRPC responses, which are also flushed to the
+// client after each write to support streaming.
+type Trailers struct {
+	next http.Handler
+}
+
+// NewTrailers returns a new Trailers handler.
+func NewTrailers(next http.Handler) *Trailers {
+	return &Trailers{next: next}
+}
+
+func (t *Trailers) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id := strconv.FormatUint(atomic.AddUint64(&trailersID, 1), 10)
+	r.Header.Set(trailersIDHeader, id)
+	defer func() {
+		r.Header.Del(trailersIDHeader)
+		trailersResponses.Lock()
+		delete(trailersResponses.responses, id)
+		trailersResponses.Unlock()
+	}()
+	trw := &trailersResponseWriter{rw: rw, id: id}
+	t.next.ServeHTTP(trw, r)
+	trw.writeTrailers()
+}
+
+// TrailersRoundTripper is a round tripper keeping the responses of the
+// servers for the Trailers handler. It also asks gRPC servers for trailers
+// with the TE header, which is removed by the forwarder as a hop-by-hop
+// header.
+type TrailersRoundTripper struct {
+	next http.RoundTripper
+}
+
+// NewTrailersRoundTripper returns a new TrailersRoundTripper.
+func NewTrailersRoundTripper(next http.RoundTripper) *TrailersRoundTripper {
+	return &TrailersRoundTripper{next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (trt *TrailersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := req.Header.Get(trailersIDHeader)
+	req.Header.Del(trailersIDHeader)
+	if isGRPC(req.Header) {
+		req.Header.Set("Te", "trailers")
+	}
+	res, err := trt.next.RoundTrip(req)
+	if err == nil && len(id) > 0 {
+		trailersResponses.Lock()
+		trailersResponses.responses[id] = res
+		trailersResponses.Unlock()
+	}
+	return res, err
+}
+
+func isGRPC(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "application/grpc")
+}
+
+func getTrailersResponse(id string) *http.Response {
+	trailersResponses.Lock()
+	defer trailersResponses.Unlock()
+	return trailersResponses.responses[id]
+}
+
+// trailersResponseWriter announces the trailers of the response of the
+// server before writing its headers.
+type trailersResponseWriter struct {
+	rw          http.ResponseWriter
+	id          string
+	wroteHeader bool
+	announced   []string
+	grpc        bool
+}
+
+func (trw *trailersResponseWriter) Header() http.Header {
+	return trw.rw.Header()
+}
+
+func (trw *trailersResponseWriter) WriteHeader(code int) {
+	if trw.wroteHeader {
+		return
+	}
+	trw.wroteHeader = true
+	if res := getTrailersResponse(trw.id); res != nil {
+		trw.grpc = isGRPC(res.Header)
+		for name := range res.Trailer {
+			trw.announced = append(trw.announced, http.CanonicalHeaderKey(name))
+		}
+		if trw.grpc {
+			// a trailers-only response has its status in the headers
+			if len(res.Header.Get("Grpc-Status")) == 0 {
+				trw.announced = appendMissing(trw.announced, grpcTrailers...)
+			}
+		}
+		if len(trw.announced) > 0 {
+			trw.rw.Header().Set("Trailer", strings.Join(trw.announced, ", "))
+		}
+	}
+	trw.rw.WriteHeader(code)
+}
+
+func (trw *trailersResponseWriter) Write(b []byte) (int, error) {
+	if !trw.wroteHeader {
+		trw.WriteHeader(http.StatusOK)
+	}
+	n, err := trw.rw.Write(b)
+	if trw.grpc {
+		trw.Flush()
+	}
+	return n, err
+}
+
+func (trw *trailersResponseWriter) Flush() {
+	if !trw.wroteHeader {
+		trw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := trw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (trw *trailersResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return trw.rw.(http.Hijacker).Hijack()
+}
+
+func (trw *trailersResponseWriter) CloseNotify() <-chan bool {
+	return closeNotify(trw.rw)
+}
+
+// writeTrailers sets the values of the announced trailers, once the body of
+// the response has been read.
+func (trw *trailersResponseWriter) writeTrailers() {
+	if len(trw.announced) == 0 {
+		return
+	}
+	res := getTrailersResponse(trw.id)
+	if res == nil {
+		return
+	}
+	header := trw.rw.Header()
+	for _, name := range trw.announced {
+		if values, ok := res.Trailer[name]; ok {
+			header[name] = values
+		}
+	}
+}
+
+func appendMissing(names []string, added ...string) []string {
+	for _, name := range added {
+		found := false
+		for _, existing := range names {
+			if existing == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulcand/oxy/utils"
+)
+
+// testForwarder forwards the requests like the oxy forwarder, without the
+// response trailers.
+type testForwarder struct {
+	url          *url.URL
+	roundTripper http.RoundTripper
+}
+
+func (f *testForwarder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	outReq, _ := http.NewRequest(r.Method, f.url.String()+r.URL.Path, r.Body)
+	utils.CopyHeaders(outReq.Header, r.Header)
+	res, err := f.roundTripper.RoundTrip(outReq)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+	utils.CopyHeaders(rw.Header(), res.Header)
+	rw.WriteHeader(res.StatusCode)
+	io.Copy(rw, res.Body)
+}
+
+func TestTrailers(t *testing.T) {
+	var received http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		rw.Header().Set("Content-Type", "application/grpc")
+		rw.Header().Set("Trailer", "Grpc-Status")
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("message"))
+		rw.Header().Set("Grpc-Status", "5")
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+
+	forwarder := &testForwarder{url: backendURL, roundTripper: NewTrailersRoundTripper(http.DefaultTransport)}
+	proxy := httptest.NewServer(NewTrailers(forwarder))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest("POST", proxy.URL+"/helloworld.Greeter/SayHello", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	assert.Equal(t, "message", string(body))
+	assert.Equal(t, "5", res.Trailer.Get("Grpc-Status"))
+	assert.Empty(t, received.Get(trailersIDHeader))
+	assert.Equal(t, "trailers", received.Get("Te"))
+	assert.Empty(t, trailersResponses.responses)
+}
+
+func TestTrailersWithoutTrailers(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("no trailers"))
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+
+	forwarder := &testForwarder{url: backendURL, roundTripper: NewTrailersRoundTripper(http.DefaultTransport)}
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	NewTrailers(forwarder).ServeHTTP(recorder, req)
+
+	assert.Equal(t, "no trailers", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("Trailer"))
+}
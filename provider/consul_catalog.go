@@ -194,6 +194,16 @@ func (provider *ConsulCatalog) getAttribute(name string, tags []string, defaultV
 	return defaultValue
 }
 
+func (provider *ConsulCatalog) getRateLimit(tags []string) *types.RateLimit {
+	labels := make(map[string]string)
+	for _, tag := range tags {
+		if kv := strings.SplitN(tag, "=", 2); len(kv) == 2 && strings.HasPrefix(kv[0], DefaultConsulCatalogTagPrefix+".") {
+			labels["traefik."+kv[0][len(DefaultConsulCatalogTagPrefix+"."):]] = kv[1]
+		}
+	}
+	return getRateLimit(labels)
+}
+
 func (provider *ConsulCatalog) getContraintTags(tags []string) []string {
 	var list []string
 
@@ -215,6 +225,7 @@ func (provider *ConsulCatalog) buildConfig(catalog []catalogUpdate) *types.Confi
 		"getBackendAddress": provider.getBackendAddress,
 		"getAttribute":      provider.getAttribute,
 		"getEntryPoints":    provider.getEntryPoints,
+		"getRateLimit":      provider.getRateLimit,
 	}
 
 	allNodes := []*api.ServiceEntry{}
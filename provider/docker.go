@@ -2,6 +2,8 @@ package provider
 
 import (
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"net/http"
 	"strconv"
 	"strings"
@@ -149,17 +151,32 @@ func (provider *Docker) Provide(configurationChan chan<- types.ConfigMessage, po
 
 func (provider *Docker) loadDockerConfig(containersInspected []dockertypes.ContainerJSON) *types.Configuration {
 	var DockerFuncMap = template.FuncMap{
-		"getBackend":        provider.getBackend,
-		"getIPAddress":      provider.getIPAddress,
-		"getPort":           provider.getPort,
-		"getWeight":         provider.getWeight,
-		"getDomain":         provider.getDomain,
-		"getProtocol":       provider.getProtocol,
-		"getPassHostHeader": provider.getPassHostHeader,
-		"getPriority":       provider.getPriority,
-		"getEntryPoints":    provider.getEntryPoints,
-		"getFrontendRule":   provider.getFrontendRule,
-		"replace":           replace,
+		"getBackend":            provider.getBackend,
+		"getIPAddress":          provider.getIPAddress,
+		"getPort":               provider.getPort,
+		"getWeight":             provider.getWeight,
+		"getDomain":             provider.getDomain,
+		"getProtocol":           provider.getProtocol,
+		"getPassHostHeader":     provider.getPassHostHeader,
+		"getCompress":           provider.getCompress,
+		"getPriority":           provider.getPriority,
+		"getEntryPoints":        provider.getEntryPoints,
+		"getFrontendRule":       provider.getFrontendRule,
+		"hasLoadBalancerLabel":  provider.hasLoadBalancerLabel,
+		"getLoadBalancerMethod": provider.getLoadBalancerMethod,
+		"getHashKey":            provider.getHashKey,
+		"getSlowStart":          provider.getSlowStart,
+		"getSticky":             provider.getSticky,
+		"getBasicAuth":          provider.getBasicAuth,
+		"getDigestAuth":         provider.getDigestAuth,
+		"getRateLimit":          provider.getRateLimit,
+		"getErrorPages":         provider.getErrorPages,
+		"getMirror":             provider.getMirror,
+		"getSplit":              provider.getSplit,
+		"getHeaders":            provider.getHeaders,
+		"getCORS":               provider.getCORS,
+		"replace":               replace,
+		"escape":                escape,
 	}
 
 	// filter containers
@@ -173,23 +190,28 @@ func (provider *Docker) loadDockerConfig(containersInspected []dockertypes.Conta
 	}
 
 	frontends := map[string]Frontend{}
+	backends := map[string]dockertypes.ContainerJSON{}
 	for _, container := range filteredContainers {
-		for frontendName, frontendRule := range provider.getFrontendName(container) {
-			frontend := frontends[frontendName]
-			frontends[frontendName] = Frontend{
-				Rule:       frontendRule,
-				Containers: append(frontend.Containers, container),
-			}
+		if _, exists := backends[provider.getBackend(container)]; !exists {
+			backends[provider.getBackend(container)] = container
+		}
+		frontendName := provider.getFrontendName(container)
+		frontend := frontends[frontendName]
+		frontends[frontendName] = Frontend{
+			Rule:       provider.getFrontendRule(container),
+			Containers: append(frontend.Containers, container),
 		}
 	}
 
 	templateObjects := struct {
 		Containers []dockertypes.ContainerJSON
 		Frontends  map[string]Frontend
+		Backends   map[string]dockertypes.ContainerJSON
 		Domain     string
 	}{
 		filteredContainers,
 		frontends,
+		backends,
 		provider.Domain,
 	}
 
@@ -227,22 +249,27 @@ func (provider *Docker) containerFilter(container dockertypes.ContainerJSON, exp
 	return true
 }
 
-func (provider *Docker) getFrontendName(container dockertypes.ContainerJSON) map[string]string {
+func (provider *Docker) getFrontendName(container dockertypes.ContainerJSON) string {
 	// Replace '.' with '-' in quoted keys because of this issue https://github.com/BurntSushi/toml/issues/78
-	frontendNames := make(map[string]string)
-	for _, rule := range provider.getFrontendRule(container) {
-		frontendNames[normalize(rule)] = rule
+	rule := provider.getFrontendRule(container)
+	name := normalize(rule)
+	// normalize drops the boolean operators and parentheses, a hash of the
+	// raw rule keeps apart the rules differing only by them
+	if strings.ContainsAny(rule, "!|&()") {
+		hash := fnv.New32a()
+		hash.Write([]byte(rule))
+		name += fmt.Sprintf("-%08x", hash.Sum32())
 	}
-	return frontendNames
+	return name
 }
 
 // GetFrontendRule returns the frontend rule for the specified container, using
 // it's label. It returns a default one (Host) if the label is not present.
-func (provider *Docker) getFrontendRule(container dockertypes.ContainerJSON) []string {
+func (provider *Docker) getFrontendRule(container dockertypes.ContainerJSON) string {
 	if label, err := getLabel(container, "traefik.frontend.rule"); err == nil {
-		return strings.Split(label, "&&")
+		return label
 	}
-	return []string{"Host:" + provider.getSubDomain(container.Name) + "." + provider.Domain}
+	return "Host:" + provider.getSubDomain(container.Name) + "." + provider.Domain
 }
 
 func (provider *Docker) getBackend(container dockertypes.ContainerJSON) string {
@@ -313,6 +340,13 @@ func (provider *Docker) getPassHostHeader(container dockertypes.ContainerJSON) s
 	return "true"
 }
 
+func (provider *Docker) getCompress(container dockertypes.ContainerJSON) string {
+	if compress, err := getLabel(container, "traefik.frontend.compress"); err == nil {
+		return compress
+	}
+	return "false"
+}
+
 func (provider *Docker) getPriority(container dockertypes.ContainerJSON) string {
 	if priority, err := getLabel(container, "traefik.frontend.priority"); err == nil {
 		return priority
@@ -320,6 +354,94 @@ func (provider *Docker) getPriority(container dockertypes.ContainerJSON) string
 	return "0"
 }
 
+func (provider *Docker) hasLoadBalancerLabel(container dockertypes.ContainerJSON) bool {
+	if _, err := getLabel(container, "traefik.backend.loadbalancer.method"); err == nil {
+		return true
+	}
+	if _, err := getLabel(container, "traefik.backend.loadbalancer.sticky"); err == nil {
+		return true
+	}
+	if _, err := getLabel(container, "traefik.backend.loadbalancer.slowStart"); err == nil {
+		return true
+	}
+	return false
+}
+
+func (provider *Docker) getLoadBalancerMethod(container dockertypes.ContainerJSON) string {
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.method"); err == nil {
+		return label
+	}
+	return "wrr"
+}
+
+func (provider *Docker) getHashKey(container dockertypes.ContainerJSON) string {
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.hashKey"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (provider *Docker) getSlowStart(container dockertypes.ContainerJSON) string {
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.slowStart"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (provider *Docker) getSticky(container dockertypes.ContainerJSON) bool {
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.sticky"); err == nil {
+		return parseSticky(label)
+	}
+	return false
+}
+
+func (provider *Docker) getBasicAuth(container dockertypes.ContainerJSON) []string {
+	if basicAuth, err := getLabel(container, "traefik.frontend.auth.basic"); err == nil {
+		return strings.Split(basicAuth, ",")
+	}
+	return []string{}
+}
+
+func (provider *Docker) getDigestAuth(container dockertypes.ContainerJSON) []string {
+	if digestAuth, err := getLabel(container, "traefik.frontend.auth.digest"); err == nil {
+		return strings.Split(digestAuth, ",")
+	}
+	return []string{}
+}
+
+func (provider *Docker) getRateLimit(container dockertypes.ContainerJSON) *types.RateLimit {
+	return getRateLimit(container.Config.Labels)
+}
+
+func (provider *Docker) getErrorPages(container dockertypes.ContainerJSON) map[string]*types.ErrorPage {
+	return getErrorPages(container.Config.Labels)
+}
+
+func (provider *Docker) getMirror(container dockertypes.ContainerJSON) *types.Mirror {
+	return getMirror(container.Config.Labels)
+}
+
+// getSplit returns the traffic split between the backends of the containers
+// sharing a frontend.
+func (provider *Docker) getSplit(containers []dockertypes.ContainerJSON) *types.Split {
+	backendLabels := make(map[string]map[string]string)
+	for _, container := range containers {
+		backendName := "backend-" + provider.getBackend(container)
+		if _, exists := backendLabels[backendName]; !exists {
+			backendLabels[backendName] = container.Config.Labels
+		}
+	}
+	return getSplit(backendLabels)
+}
+
+func (provider *Docker) getHeaders(container dockertypes.ContainerJSON) *types.Headers {
+	return getHeaders(container.Config.Labels)
+}
+
+func (provider *Docker) getCORS(container dockertypes.ContainerJSON) *types.CORS {
+	return getCORS(container.Config.Labels)
+}
+
 func (provider *Docker) getEntryPoints(container dockertypes.ContainerJSON) []string {
 	if entryPoints, err := getLabel(container, "traefik.frontend.entryPoints"); err == nil {
 		return strings.Split(entryPoints, ",")
@@ -2,7 +2,6 @@ package provider
 
 import (
 	"reflect"
-	"sort"
 	"strings"
 	"testing"
 
@@ -20,7 +19,7 @@ func TestDockerGetFrontendName(t *testing.T) {
 
 	containers := []struct {
 		container docker.ContainerJSON
-		expected  []string
+		expected  string
 	}{
 		{
 			container: docker.ContainerJSON{
@@ -29,7 +28,7 @@ func TestDockerGetFrontendName(t *testing.T) {
 				},
 				Config: &container.Config{},
 			},
-			expected: []string{"Host-foo-docker-localhost"},
+			expected: "Host-foo-docker-localhost",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -42,7 +41,7 @@ func TestDockerGetFrontendName(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"Headers-User-Agent-bat-0-1-0"},
+			expected: "Headers-User-Agent-bat-0-1-0",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -55,7 +54,7 @@ func TestDockerGetFrontendName(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"Host-foo-bar"},
+			expected: "Host-foo-bar",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -68,7 +67,7 @@ func TestDockerGetFrontendName(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"Path-test"},
+			expected: "Path-test",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -81,7 +80,7 @@ func TestDockerGetFrontendName(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"PathPrefix-test2"},
+			expected: "PathPrefix-test2",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -94,22 +93,27 @@ func TestDockerGetFrontendName(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"Host-foo-bar", "PathPrefix-test2"},
+			expected: "PathPrefix-test2-Host-foo-bar-cc34f09a",
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.frontend.rule": "PathPrefix:/test2||Host:foo.bar",
+					},
+				},
+			},
+			expected: "PathPrefix-test2-Host-foo-bar-ddc262f2",
 		},
 	}
 
 	for _, e := range containers {
 		actual := provider.getFrontendName(e.container)
-		keys := []string{}
-		for key := range actual {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-		sort.Strings(e.expected)
-		for index, key := range keys {
-			if key != e.expected[index] {
-				t.Fatalf("expected %q, got %q", e.expected, actual)
-			}
+		if actual != e.expected {
+			t.Fatalf("expected %q, got %q", e.expected, actual)
 		}
 	}
 }
@@ -121,7 +125,7 @@ func TestDockerGetFrontendRule(t *testing.T) {
 
 	containers := []struct {
 		container docker.ContainerJSON
-		expected  []string
+		expected  string
 	}{
 		{
 			container: docker.ContainerJSON{
@@ -130,7 +134,7 @@ func TestDockerGetFrontendRule(t *testing.T) {
 				},
 				Config: &container.Config{},
 			},
-			expected: []string{"Host:foo.docker.localhost"},
+			expected: "Host:foo.docker.localhost",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -139,7 +143,7 @@ func TestDockerGetFrontendRule(t *testing.T) {
 				},
 				Config: &container.Config{},
 			},
-			expected: []string{"Host:bar.docker.localhost"},
+			expected: "Host:bar.docker.localhost",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -152,7 +156,7 @@ func TestDockerGetFrontendRule(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"Host:foo.bar"},
+			expected: "Host:foo.bar",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -165,7 +169,7 @@ func TestDockerGetFrontendRule(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"Path:/test"},
+			expected: "Path:/test",
 		},
 		{
 			container: docker.ContainerJSON{
@@ -178,18 +182,14 @@ func TestDockerGetFrontendRule(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"Path:/test", "Host:foo.bar"},
+			expected: "Path:/test&&Host:foo.bar",
 		},
 	}
 
 	for _, e := range containers {
 		actual := provider.getFrontendRule(e.container)
-		sort.Strings(actual)
-		sort.Strings(e.expected)
-		for index, rule := range actual {
-			if rule != e.expected[index] {
-				t.Fatalf("expected %q, got %q", e.expected, actual)
-			}
+		if actual != e.expected {
+			t.Fatalf("expected %q, got %q", e.expected, actual)
 		}
 	}
 }
@@ -472,6 +472,42 @@ func TestDockerGetWeight(t *testing.T) {
 	}
 }
 
+func TestDockerGetSplit(t *testing.T) {
+	provider := &Docker{}
+
+	stable := docker.ContainerJSON{
+		ContainerJSONBase: &docker.ContainerJSONBase{
+			Name: "app-v1",
+		},
+		Config: &container.Config{
+			Labels: map[string]string{
+				"traefik.frontend.rule":         "Host:app.docker.localhost",
+				"traefik.frontend.split.weight": "9",
+			},
+		},
+	}
+	canary := docker.ContainerJSON{
+		ContainerJSONBase: &docker.ContainerJSONBase{
+			Name: "app-v2",
+		},
+		Config: &container.Config{
+			Labels: map[string]string{
+				"traefik.frontend.rule":         "Host:app.docker.localhost",
+				"traefik.frontend.split.weight": "1",
+			},
+		},
+	}
+
+	if actual := provider.getSplit([]docker.ContainerJSON{stable}); actual != nil {
+		t.Fatalf("expected nil, got %+v", actual)
+	}
+	expected := &types.Split{Backends: map[string]int{"backend-app-v1": 9, "backend-app-v2": 1}}
+	actual := provider.getSplit([]docker.ContainerJSON{stable, canary})
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
+
 func TestDockerGetDomain(t *testing.T) {
 	provider := &Docker{
 		Domain: "docker.localhost",
@@ -542,6 +578,19 @@ func TestDockerGetProtocol(t *testing.T) {
 			},
 			expected: "https",
 		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "grpc",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.protocol": "h2c",
+					},
+				},
+			},
+			expected: "h2c",
+		},
 	}
 
 	for _, e := range containers {
@@ -590,6 +639,263 @@ func TestDockerGetPassHostHeader(t *testing.T) {
 	}
 }
 
+func TestDockerGetCompress(t *testing.T) {
+	provider := &Docker{}
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "foo",
+				},
+				Config: &container.Config{},
+			},
+			expected: "false",
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.frontend.compress": "true",
+					},
+				},
+			},
+			expected: "true",
+		},
+	}
+
+	for _, e := range containers {
+		actual := provider.getCompress(e.container)
+		if actual != e.expected {
+			t.Fatalf("expected %q, got %q", e.expected, actual)
+		}
+	}
+}
+
+func TestDockerGetSticky(t *testing.T) {
+	provider := &Docker{}
+	containers := []struct {
+		container      docker.ContainerJSON
+		expectedLabel  bool
+		expectedMethod string
+		expectedSticky bool
+	}{
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "foo",
+				},
+				Config: &container.Config{},
+			},
+			expectedLabel:  false,
+			expectedMethod: "wrr",
+			expectedSticky: false,
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.backend.loadbalancer.sticky": "true",
+					},
+				},
+			},
+			expectedLabel:  true,
+			expectedMethod: "wrr",
+			expectedSticky: true,
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.backend.loadbalancer.method": "drr",
+					},
+				},
+			},
+			expectedLabel:  true,
+			expectedMethod: "drr",
+			expectedSticky: false,
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.backend.loadbalancer.sticky": "yes",
+					},
+				},
+			},
+			expectedLabel:  true,
+			expectedMethod: "wrr",
+			expectedSticky: false,
+		},
+	}
+
+	for _, e := range containers {
+		if actual := provider.hasLoadBalancerLabel(e.container); actual != e.expectedLabel {
+			t.Fatalf("expected %v, got %v", e.expectedLabel, actual)
+		}
+		if actual := provider.getLoadBalancerMethod(e.container); actual != e.expectedMethod {
+			t.Fatalf("expected %q, got %q", e.expectedMethod, actual)
+		}
+		if actual := provider.getSticky(e.container); actual != e.expectedSticky {
+			t.Fatalf("expected %v, got %v", e.expectedSticky, actual)
+		}
+	}
+}
+
+func TestDockerGetBasicAuth(t *testing.T) {
+	provider := &Docker{}
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []string
+	}{
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "foo",
+				},
+				Config: &container.Config{},
+			},
+			expected: []string{},
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.frontend.auth.basic": "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/,test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0",
+					},
+				},
+			},
+			expected: []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+		},
+	}
+
+	for _, e := range containers {
+		actual := provider.getBasicAuth(e.container)
+		if !reflect.DeepEqual(actual, e.expected) {
+			t.Fatalf("expected %q, got %q", e.expected, actual)
+		}
+	}
+}
+
+func TestDockerGetRateLimit(t *testing.T) {
+	provider := &Docker{}
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  *types.RateLimit
+	}{
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "foo",
+				},
+				Config: &container.Config{},
+			},
+			expected: nil,
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.frontend.rateLimit.extractorFunc":           "request.header.X-Api-Key",
+						"traefik.frontend.rateLimit.rateSet.api.period":      "10s",
+						"traefik.frontend.rateLimit.rateSet.api.average":     "100",
+						"traefik.frontend.rateLimit.rateSet.api.burst":       "200",
+						"traefik.frontend.rateLimit.rateSet.slow.average":    "5",
+						"traefik.frontend.rateLimit.rateSet.slow.burst":      "invalid",
+						"traefik.frontend.rateLimit.rateSet.burstOnly.burst": "5",
+					},
+				},
+			},
+			expected: &types.RateLimit{
+				ExtractorFunc: "request.header.X-Api-Key",
+				RateSet: map[string]*types.Rate{
+					"api": {Period: "10s", Average: 100, Burst: 200},
+				},
+			},
+		},
+	}
+
+	for _, e := range containers {
+		actual := provider.getRateLimit(e.container)
+		if !reflect.DeepEqual(actual, e.expected) {
+			t.Fatalf("expected %+v, got %+v", e.expected, actual)
+		}
+	}
+}
+
+func TestDockerGetHeaders(t *testing.T) {
+	provider := &Docker{}
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  *types.Headers
+	}{
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "foo",
+				},
+				Config: &container.Config{},
+			},
+			expected: nil,
+		},
+		{
+			container: docker.ContainerJSON{
+				ContainerJSONBase: &docker.ContainerJSONBase{
+					Name: "test",
+				},
+				Config: &container.Config{
+					Labels: map[string]string{
+						"traefik.frontend.headers.customRequestHeaders":  "X-Forwarded-Proto:https || X-Debug:",
+						"traefik.frontend.headers.customResponseHeaders": "Server:",
+						"traefik.frontend.headers.allowedHosts":          "foo.localhost,bar.localhost",
+						"traefik.frontend.headers.SSLRedirect":           "true",
+						"traefik.frontend.headers.STSSeconds":            "315360000",
+						"traefik.frontend.headers.STSPreload":            "true",
+						"traefik.frontend.headers.frameDeny":             "true",
+						"traefik.frontend.headers.contentTypeNosniff":    "invalid",
+					},
+				},
+			},
+			expected: &types.Headers{
+				CustomRequestHeaders:  map[string]string{"X-Forwarded-Proto": "https", "X-Debug": ""},
+				CustomResponseHeaders: map[string]string{"Server": ""},
+				AllowedHosts:          []string{"foo.localhost", "bar.localhost"},
+				SSLRedirect:           true,
+				STSSeconds:            315360000,
+				STSPreload:            true,
+				FrameDeny:             true,
+			},
+		},
+	}
+
+	for _, e := range containers {
+		actual := provider.getHeaders(e.container)
+		if !reflect.DeepEqual(actual, e.expected) {
+			t.Fatalf("expected %+v, got %+v", e.expected, actual)
+		}
+	}
+}
+
 func TestDockerGetLabel(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
@@ -1055,6 +1361,98 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			containers: []docker.ContainerJSON{
+				{
+					ContainerJSONBase: &docker.ContainerJSONBase{
+						Name: "test1",
+					},
+					Config: &container.Config{
+						Labels: map[string]string{
+							"traefik.frontend.rule": "Host:a.com",
+						},
+					},
+					NetworkSettings: &docker.NetworkSettings{
+						NetworkSettingsBase: docker.NetworkSettingsBase{
+							Ports: nat.PortMap{
+								"80/tcp": {},
+							},
+						},
+						Networks: map[string]*network.EndpointSettings{
+							"bridge": {
+								IPAddress: "127.0.0.1",
+							},
+						},
+					},
+				},
+				{
+					ContainerJSONBase: &docker.ContainerJSONBase{
+						Name: "test2",
+					},
+					Config: &container.Config{
+						Labels: map[string]string{
+							"traefik.frontend.rule": "!Host:a.com",
+						},
+					},
+					NetworkSettings: &docker.NetworkSettings{
+						NetworkSettingsBase: docker.NetworkSettingsBase{
+							Ports: nat.PortMap{
+								"80/tcp": {},
+							},
+						},
+						Networks: map[string]*network.EndpointSettings{
+							"bridge": {
+								IPAddress: "127.0.0.1",
+							},
+						},
+					},
+				},
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-a-com": {
+					Backend:        "backend-test1",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-a-com": {
+							Rule: "Host:a.com",
+						},
+					},
+				},
+				"frontend-Host-a-com-3f7b4d58": {
+					Backend:        "backend-test2",
+					PassHostHeader: true,
+					EntryPoints:    []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-a-com-3f7b4d58": {
+							Rule: "!Host:a.com",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test1": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 1,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer:   nil,
+				},
+				"backend-test2": {
+					Servers: map[string]types.Server{
+						"server-test2": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 1,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer:   nil,
+				},
+			},
+		},
 	}
 
 	provider := &Docker{
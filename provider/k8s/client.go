@@ -25,6 +25,7 @@ type Client interface {
 	GetIngresses(labelSelector string, predicate func(Ingress) bool) ([]Ingress, error)
 	GetService(name, namespace string) (Service, error)
 	GetEndpoints(name, namespace string) (Endpoints, error)
+	GetSecret(name, namespace string) (Secret, error)
 	WatchAll(labelSelector string, stopCh <-chan bool) (chan interface{}, chan error, error)
 }
 
@@ -139,6 +140,22 @@ func (c *clientImpl) WatchEndpoints(labelSelector string, stopCh <-chan bool) (c
 	return c.watch(getURL, labelSelector, stopCh)
 }
 
+// GetSecret returns the named secret from the named namespace
+func (c *clientImpl) GetSecret(name, namespace string) (Secret, error) {
+	getURL := c.endpointURL + APIEndpoint + namespaces + namespace + "/secrets/" + name
+
+	body, err := c.do(c.request(getURL, ""))
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to create secrets request: GET %q : %v", getURL, err)
+	}
+
+	var secret Secret
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return Secret{}, fmt.Errorf("failed to decode secret resource: %v", err)
+	}
+	return secret, nil
+}
+
 // WatchAll returns events in the cluster
 func (c *clientImpl) WatchAll(labelSelector string, stopCh <-chan bool) (chan interface{}, chan error, error) {
 	watchCh := make(chan interface{}, 10)
@@ -0,0 +1,27 @@
+package k8s
+
+const (
+	// TLSCertKey is the key of the certificate in the data of a TLS secret.
+	TLSCertKey = "tls.crt"
+	// TLSPrivateKeyKey is the key of the private key in the data of a TLS
+	// secret.
+	TLSPrivateKeyKey = "tls.key"
+)
+
+// Secret holds secret data of a certain type. The total bytes of the values in
+// the Data field must be less than 1MB.
+type Secret struct {
+	TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	// More info: http://releases.k8s.io/HEAD/docs/devel/api-conventions.md#metadata
+	ObjectMeta `json:"metadata,omitempty"`
+
+	// Data contains the secret data. Each key must be a valid DNS_SUBDOMAIN
+	// or leading dot followed by valid DNS_SUBDOMAIN.
+	// The serialized form of the secret data is a base64 encoded string,
+	// representing the arbitrary (possibly non-string) data value here.
+	Data map[string][]byte `json:"data,omitempty"`
+
+	// Used to facilitate programmatic handling of secret data.
+	Type string `json:"type,omitempty"`
+}
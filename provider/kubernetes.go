@@ -21,6 +21,8 @@ const (
 	serviceAccountToken  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
 	serviceAccountCACert = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
 	defaultKubeEndpoint  = "http://127.0.0.1:8080"
+
+	annotationWhitelistSourceRange = "ingress.kubernetes.io/whitelist-source-range"
 )
 
 // Namespaces holds kubernetes namespaces
@@ -192,23 +194,27 @@ func (provider *Kubernetes) loadIngresses(k8sClient k8s.Client) (*types.Configur
 		return nil, err
 	}
 	templateObjects := types.Configuration{
-		map[string]*types.Backend{},
-		map[string]*types.Frontend{},
+		Backends:  map[string]*types.Backend{},
+		Frontends: map[string]*types.Frontend{},
 	}
 	PassHostHeader := provider.getPassHostHeader()
+	loadedSecrets := make(map[string]bool)
 	for _, i := range ingresses {
 		for _, r := range i.Spec.Rules {
 			for _, pa := range r.HTTP.Paths {
 				if _, exists := templateObjects.Backends[r.Host+pa.Path]; !exists {
 					templateObjects.Backends[r.Host+pa.Path] = &types.Backend{
-						Servers: make(map[string]types.Server),
+						Servers:      make(map[string]types.Server),
+						LoadBalancer: getLoadBalancer(i.Annotations),
 					}
 				}
 				if _, exists := templateObjects.Frontends[r.Host+pa.Path]; !exists {
 					templateObjects.Frontends[r.Host+pa.Path] = &types.Frontend{
-						Backend:        r.Host + pa.Path,
-						PassHostHeader: PassHostHeader,
-						Routes:         make(map[string]types.Route),
+						Backend:              r.Host + pa.Path,
+						PassHostHeader:       PassHostHeader,
+						Routes:               make(map[string]types.Route),
+						WhitelistSourceRange: getWhitelistSourceRange(i),
+						RateLimit:            getRateLimit(i.Annotations),
 					}
 				}
 				if len(r.Host) > 0 {
@@ -279,10 +285,59 @@ func (provider *Kubernetes) loadIngresses(k8sClient k8s.Client) (*types.Configur
 				}
 			}
 		}
+		for _, ingressTLS := range i.Spec.TLS {
+			if len(ingressTLS.SecretName) == 0 {
+				continue
+			}
+			secretName := i.ObjectMeta.Namespace + "/" + ingressTLS.SecretName
+			if loadedSecrets[secretName] {
+				continue
+			}
+			tlsCertificate, err := getTLSCertificate(k8sClient, ingressTLS.SecretName, i.ObjectMeta.Namespace)
+			if err != nil {
+				log.Errorf("Error loading TLS secret %s: %v", secretName, err)
+				continue
+			}
+			loadedSecrets[secretName] = true
+			templateObjects.TLSCertificates = append(templateObjects.TLSCertificates, tlsCertificate)
+		}
 	}
 	return &templateObjects, nil
 }
 
+// getTLSCertificate returns the certificate and key of a TLS secret.
+func getTLSCertificate(k8sClient k8s.Client, name, namespace string) (*types.TLSCertificate, error) {
+	secret, err := k8sClient.GetSecret(name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	cert, certExists := secret.Data[k8s.TLSCertKey]
+	key, keyExists := secret.Data[k8s.TLSPrivateKeyKey]
+	if !certExists || !keyExists {
+		return nil, fmt.Errorf("missing %s or %s", k8s.TLSCertKey, k8s.TLSPrivateKeyKey)
+	}
+	return &types.TLSCertificate{
+		CertFile: string(cert),
+		KeyFile:  string(key),
+	}, nil
+}
+
+// getWhitelistSourceRange returns the comma separated CIDRs of the
+// ingress.kubernetes.io/whitelist-source-range annotation.
+func getWhitelistSourceRange(ingress k8s.Ingress) []string {
+	annotation, exists := ingress.Annotations[annotationWhitelistSourceRange]
+	if !exists || len(strings.TrimSpace(annotation)) == 0 {
+		return nil
+	}
+	var sourceRanges []string
+	for _, sourceRange := range strings.Split(annotation, ",") {
+		if sourceRange = strings.TrimSpace(sourceRange); len(sourceRange) > 0 {
+			sourceRanges = append(sourceRanges, sourceRange)
+		}
+	}
+	return sourceRanges
+}
+
 func endpointPortNumber(servicePort k8s.ServicePort, endpointPorts []k8s.EndpointPort) int {
 	if len(endpointPorts) > 0 {
 		//name is optional if there is only one port
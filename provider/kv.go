@@ -124,6 +124,7 @@ func (provider *Kv) loadConfig() *types.Configuration {
 		"Get":      provider.get,
 		"SplitGet": provider.splitGet,
 		"Last":     provider.last,
+		"Escape":   escape,
 	}
 
 	configuration, err := provider.getConfiguration("templates/kv.tmpl", KvFuncMap, templateObjects)
@@ -136,7 +137,10 @@ func (provider *Kv) loadConfig() *types.Configuration {
 func (provider *Kv) list(keys ...string) []string {
 	joinedKeys := strings.Join(keys, "")
 	keysPairs, err := provider.kvclient.List(joinedKeys)
-	if err != nil {
+	if err == store.ErrKeyNotFound {
+		log.Debugf("No keys in %s", joinedKeys)
+		return nil
+	} else if err != nil {
 		log.Errorf("Error getting keys %s %s ", joinedKeys, err)
 		return nil
 	}
@@ -117,17 +117,32 @@ func (provider *Marathon) Provide(configurationChan chan<- types.ConfigMessage,
 
 func (provider *Marathon) loadMarathonConfig() *types.Configuration {
 	var MarathonFuncMap = template.FuncMap{
-		"getBackend":         provider.getBackend,
-		"getPort":            provider.getPort,
-		"getWeight":          provider.getWeight,
-		"getDomain":          provider.getDomain,
-		"getProtocol":        provider.getProtocol,
-		"getPassHostHeader":  provider.getPassHostHeader,
-		"getPriority":        provider.getPriority,
-		"getEntryPoints":     provider.getEntryPoints,
-		"getFrontendRule":    provider.getFrontendRule,
-		"getFrontendBackend": provider.getFrontendBackend,
-		"replace":            replace,
+		"getBackend":            provider.getBackend,
+		"getPort":               provider.getPort,
+		"getWeight":             provider.getWeight,
+		"getDomain":             provider.getDomain,
+		"getProtocol":           provider.getProtocol,
+		"getPassHostHeader":     provider.getPassHostHeader,
+		"getCompress":           provider.getCompress,
+		"getPriority":           provider.getPriority,
+		"getEntryPoints":        provider.getEntryPoints,
+		"getFrontendRule":       provider.getFrontendRule,
+		"getFrontendBackend":    provider.getFrontendBackend,
+		"hasLoadBalancerLabels": provider.hasLoadBalancerLabels,
+		"getLoadBalancerMethod": provider.getLoadBalancerMethod,
+		"getHashKey":            provider.getHashKey,
+		"getSlowStart":          provider.getSlowStart,
+		"getSticky":             provider.getSticky,
+		"getBasicAuth":          provider.getBasicAuth,
+		"getDigestAuth":         provider.getDigestAuth,
+		"getRateLimit":          provider.getRateLimit,
+		"getErrorPages":         provider.getErrorPages,
+		"getMirror":             provider.getMirror,
+		"getSplit":              provider.getSplit,
+		"getHeaders":            provider.getHeaders,
+		"getCORS":               provider.getCORS,
+		"replace":               replace,
+		"escape":                escape,
 	}
 
 	applications, err := provider.marathonClient.Applications(nil)
@@ -152,13 +167,25 @@ func (provider *Marathon) loadMarathonConfig() *types.Configuration {
 		return applicationFilter(app, filteredTasks)
 	}, applications.Apps).([]marathon.Application)
 
+	// the load balancer of a backend shared by several applications is
+	// defined once, by the first application with load balancer labels
+	backends := map[string]marathon.Application{}
+	for _, app := range filteredApps {
+		backendName := provider.getFrontendBackend(app)
+		if backend, exists := backends[backendName]; !exists || !provider.hasLoadBalancerLabels(backend) {
+			backends[backendName] = app
+		}
+	}
+
 	templateObjects := struct {
 		Applications []marathon.Application
 		Tasks        []marathon.Task
+		Backends     map[string]marathon.Application
 		Domain       string
 	}{
 		filteredApps,
 		filteredTasks,
+		backends,
 		provider.Domain,
 	}
 
@@ -330,6 +357,13 @@ func (provider *Marathon) getPassHostHeader(application marathon.Application) st
 	return "true"
 }
 
+func (provider *Marathon) getCompress(application marathon.Application) string {
+	if compress, err := provider.getLabel(application, "traefik.frontend.compress"); err == nil {
+		return compress
+	}
+	return "false"
+}
+
 func (provider *Marathon) getPriority(application marathon.Application) string {
 	if priority, err := provider.getLabel(application, "traefik.frontend.priority"); err == nil {
 		return priority
@@ -337,6 +371,72 @@ func (provider *Marathon) getPriority(application marathon.Application) string {
 	return "0"
 }
 
+func (provider *Marathon) getBasicAuth(application marathon.Application) []string {
+	if basicAuth, err := provider.getLabel(application, "traefik.frontend.auth.basic"); err == nil {
+		return strings.Split(basicAuth, ",")
+	}
+	return []string{}
+}
+
+func (provider *Marathon) getDigestAuth(application marathon.Application) []string {
+	if digestAuth, err := provider.getLabel(application, "traefik.frontend.auth.digest"); err == nil {
+		return strings.Split(digestAuth, ",")
+	}
+	return []string{}
+}
+
+func (provider *Marathon) getRateLimit(application marathon.Application) *types.RateLimit {
+	if application.Labels == nil {
+		return nil
+	}
+	return getRateLimit(*application.Labels)
+}
+
+func (provider *Marathon) getErrorPages(application marathon.Application) map[string]*types.ErrorPage {
+	if application.Labels == nil {
+		return nil
+	}
+	return getErrorPages(*application.Labels)
+}
+
+func (provider *Marathon) getMirror(application marathon.Application) *types.Mirror {
+	if application.Labels == nil {
+		return nil
+	}
+	return getMirror(*application.Labels)
+}
+
+// getSplit returns the traffic split between the backends of the applications
+// sharing the frontend rule of the given application.
+func (provider *Marathon) getSplit(application marathon.Application, applications []marathon.Application) *types.Split {
+	rule := provider.getFrontendRule(application)
+	backendLabels := make(map[string]map[string]string)
+	for _, app := range applications {
+		if app.Labels == nil || provider.getFrontendRule(app) != rule {
+			continue
+		}
+		backendName := "backend" + provider.getFrontendBackend(app)
+		if _, exists := backendLabels[backendName]; !exists {
+			backendLabels[backendName] = *app.Labels
+		}
+	}
+	return getSplit(backendLabels)
+}
+
+func (provider *Marathon) getHeaders(application marathon.Application) *types.Headers {
+	if application.Labels == nil {
+		return nil
+	}
+	return getHeaders(*application.Labels)
+}
+
+func (provider *Marathon) getCORS(application marathon.Application) *types.CORS {
+	if application.Labels == nil {
+		return nil
+	}
+	return getCORS(*application.Labels)
+}
+
 func (provider *Marathon) getEntryPoints(application marathon.Application) []string {
 	if entryPoints, err := provider.getLabel(application, "traefik.frontend.entryPoints"); err == nil {
 		return strings.Split(entryPoints, ",")
@@ -344,6 +444,47 @@ func (provider *Marathon) getEntryPoints(application marathon.Application) []str
 	return []string{}
 }
 
+func (provider *Marathon) hasLoadBalancerLabels(application marathon.Application) bool {
+	if _, err := provider.getLabel(application, "traefik.backend.loadbalancer.method"); err == nil {
+		return true
+	}
+	if _, err := provider.getLabel(application, "traefik.backend.loadbalancer.sticky"); err == nil {
+		return true
+	}
+	if _, err := provider.getLabel(application, "traefik.backend.loadbalancer.slowStart"); err == nil {
+		return true
+	}
+	return false
+}
+
+func (provider *Marathon) getLoadBalancerMethod(application marathon.Application) string {
+	if label, err := provider.getLabel(application, "traefik.backend.loadbalancer.method"); err == nil {
+		return label
+	}
+	return "wrr"
+}
+
+func (provider *Marathon) getHashKey(application marathon.Application) string {
+	if label, err := provider.getLabel(application, "traefik.backend.loadbalancer.hashKey"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (provider *Marathon) getSlowStart(application marathon.Application) string {
+	if label, err := provider.getLabel(application, "traefik.backend.loadbalancer.slowStart"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (provider *Marathon) getSticky(application marathon.Application) bool {
+	if sticky, err := provider.getLabel(application, "traefik.backend.loadbalancer.sticky"); err == nil {
+		return parseSticky(sticky)
+	}
+	return false
+}
+
 // getFrontendRule returns the frontend rule for the specified application, using
 // it's label. It returns a default one (Host) if the label is not present.
 func (provider *Marathon) getFrontendRule(application marathon.Application) string {
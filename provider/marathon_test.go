@@ -137,6 +137,62 @@ func TestMarathonLoadConfig(t *testing.T) {
 	}
 }
 
+func TestMarathonLoadConfigSharedBackend(t *testing.T) {
+	applications := &marathon.Applications{
+		Apps: []marathon.Application{
+			{
+				ID:    "/foo",
+				Ports: []int{80},
+				Labels: &map[string]string{
+					"traefik.backend": "-shared",
+				},
+			},
+			{
+				ID:    "/bar",
+				Ports: []int{80},
+				Labels: &map[string]string{
+					"traefik.backend":                     "-shared",
+					"traefik.backend.loadbalancer.method": "drr",
+					"traefik.backend.loadbalancer.sticky": "true",
+				},
+			},
+			{
+				ID:    "/baz",
+				Ports: []int{80},
+				Labels: &map[string]string{
+					"traefik.backend":                     "-shared",
+					"traefik.backend.loadbalancer.method": "wrr",
+				},
+			},
+		},
+	}
+	tasks := &marathon.Tasks{
+		Tasks: []marathon.Task{
+			{ID: "foo", AppID: "/foo", Host: "127.0.0.1", Ports: []int{80}},
+			{ID: "bar", AppID: "/bar", Host: "127.0.0.2", Ports: []int{80}},
+			{ID: "baz", AppID: "/baz", Host: "127.0.0.3", Ports: []int{80}},
+		},
+	}
+	fakeClient := newFakeClient(false, applications, false, tasks)
+	provider := &Marathon{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		marathonClient:   fakeClient,
+	}
+	actualConfig := provider.loadMarathonConfig()
+	if actualConfig == nil {
+		t.Fatalf("Applications sharing a backend should give a valid configuration")
+	}
+	backend, ok := actualConfig.Backends["backend-shared"]
+	if !ok || len(backend.Servers) != 3 {
+		t.Fatalf("expected a backend with 3 servers, got %#v", actualConfig.Backends)
+	}
+	expected := &types.LoadBalancer{Method: "drr", Sticky: true}
+	if !reflect.DeepEqual(backend.LoadBalancer, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, backend.LoadBalancer)
+	}
+}
+
 func TestMarathonTaskFilter(t *testing.T) {
 	cases := []struct {
 		task             marathon.Task
@@ -823,6 +879,234 @@ func TestMarathonGetPassHostHeader(t *testing.T) {
 	}
 }
 
+func TestMarathonGetSticky(t *testing.T) {
+	provider := &Marathon{}
+
+	applications := []struct {
+		application    marathon.Application
+		expectedLabels bool
+		expected       bool
+	}{
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{}},
+			expectedLabels: false,
+			expected:       false,
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.backend.loadbalancer.sticky": "true",
+				},
+			},
+			expectedLabels: true,
+			expected:       true,
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.backend.loadbalancer.sticky": "True ",
+				},
+			},
+			expectedLabels: true,
+			expected:       false,
+		},
+	}
+
+	for _, a := range applications {
+		if actual := provider.hasLoadBalancerLabels(a.application); actual != a.expectedLabels {
+			t.Fatalf("expected %v, got %v", a.expectedLabels, actual)
+		}
+		if actual := provider.getSticky(a.application); actual != a.expected {
+			t.Fatalf("expected %v, got %v", a.expected, actual)
+		}
+	}
+}
+
+func TestMarathonGetCORS(t *testing.T) {
+	provider := &Marathon{}
+
+	applications := []struct {
+		application marathon.Application
+		expected    *types.CORS
+	}{
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{}},
+			expected: nil,
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.frontend.cors.allowedOrigins":   "https://*.example.com, https://example.com",
+					"traefik.frontend.cors.allowedMethods":   "GET,PUT",
+					"traefik.frontend.cors.allowCredentials": "true",
+					"traefik.frontend.cors.maxAge":           "600",
+				},
+			},
+			expected: &types.CORS{
+				AllowedOrigins:   []string{"https://*.example.com", "https://example.com"},
+				AllowedMethods:   []string{"GET", "PUT"},
+				AllowCredentials: true,
+				MaxAge:           600,
+			},
+		},
+	}
+
+	for _, a := range applications {
+		actual := provider.getCORS(a.application)
+		if !reflect.DeepEqual(actual, a.expected) {
+			t.Fatalf("expected %+v, got %+v", a.expected, actual)
+		}
+	}
+}
+
+func TestMarathonGetErrorPages(t *testing.T) {
+	provider := &Marathon{}
+
+	applications := []struct {
+		application marathon.Application
+		expected    map[string]*types.ErrorPage
+	}{
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{}},
+			expected: nil,
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.frontend.errors.network.status":  "502-504",
+					"traefik.frontend.errors.network.backend": "backend-errors",
+					"traefik.frontend.errors.network.query":   "/{status}.html",
+					"traefik.frontend.errors.server.status":   "500, 501",
+					"traefik.frontend.errors.server.backend":  "backend-errors",
+					"traefik.frontend.errors.server.foo":      "bar",
+				},
+			},
+			expected: map[string]*types.ErrorPage{
+				"network": {Status: []string{"502-504"}, Backend: "backend-errors", Query: "/{status}.html"},
+				"server":  {Status: []string{"500", "501"}, Backend: "backend-errors"},
+			},
+		},
+	}
+
+	for _, a := range applications {
+		actual := provider.getErrorPages(a.application)
+		if !reflect.DeepEqual(actual, a.expected) {
+			t.Fatalf("expected %+v, got %+v", a.expected, actual)
+		}
+	}
+}
+
+func TestMarathonGetMirror(t *testing.T) {
+	provider := &Marathon{}
+	ten, zero := 10, 0
+
+	applications := []struct {
+		application marathon.Application
+		expected    *types.Mirror
+	}{
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.frontend.mirror.percent": "10",
+				}},
+			expected: nil,
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.frontend.mirror.backend":     "backend-v2",
+					"traefik.frontend.mirror.percent":     "10",
+					"traefik.frontend.mirror.maxBodySize": "4096",
+				},
+			},
+			expected: &types.Mirror{Backend: "backend-v2", Percent: &ten, MaxBodySize: 4096},
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.frontend.mirror.backend": "backend-v2",
+				},
+			},
+			expected: &types.Mirror{Backend: "backend-v2"},
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.frontend.mirror.backend": "backend-v2",
+					"traefik.frontend.mirror.percent": "0",
+				},
+			},
+			expected: &types.Mirror{Backend: "backend-v2", Percent: &zero},
+		},
+		{
+			application: marathon.Application{
+				Labels: &map[string]string{
+					"traefik.frontend.mirror.backend": "backend-v2",
+					"traefik.frontend.mirror.percent": "all",
+				},
+			},
+			expected: &types.Mirror{Backend: "backend-v2"},
+		},
+	}
+
+	for _, a := range applications {
+		actual := provider.getMirror(a.application)
+		if !reflect.DeepEqual(actual, a.expected) {
+			t.Fatalf("expected %+v, got %+v", a.expected, actual)
+		}
+	}
+}
+
+func TestMarathonGetSplit(t *testing.T) {
+	provider := &Marathon{Domain: "docker.localhost"}
+
+	stable := marathon.Application{
+		ID: "/app-v1",
+		Labels: &map[string]string{
+			"traefik.frontend.rule":         "Host:app.docker.localhost",
+			"traefik.frontend.split.weight": "9",
+			"traefik.frontend.split.sticky": "True",
+		},
+	}
+	canary := marathon.Application{
+		ID: "/app-v2",
+		Labels: &map[string]string{
+			"traefik.frontend.rule":         "Host:app.docker.localhost",
+			"traefik.frontend.split.weight": "1",
+		},
+	}
+	other := marathon.Application{
+		ID:     "/other",
+		Labels: &map[string]string{},
+	}
+	applications := []marathon.Application{stable, canary, other}
+
+	expected := &types.Split{
+		Backends: map[string]int{"backend-app-v1": 9, "backend-app-v2": 1},
+		Sticky:   true,
+	}
+	for _, application := range []marathon.Application{stable, canary} {
+		actual := provider.getSplit(application, applications)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, actual)
+		}
+	}
+	if actual := provider.getSplit(other, applications); actual != nil {
+		t.Fatalf("expected nil, got %+v", actual)
+	}
+
+	invalid := getSplit(map[string]map[string]string{
+		"backend-app-v1": {"traefik.frontend.split.weight": "9", "traefik.frontend.split.sticky": "yes"},
+		"backend-app-v2": {"traefik.frontend.split.weight": "1"},
+	})
+	if invalid == nil || invalid.Sticky {
+		t.Fatalf("expected a split without sticky sessions, got %+v", invalid)
+	}
+}
+
 func TestMarathonGetEntryPoints(t *testing.T) {
 	provider := &Marathon{}
 
@@ -132,6 +132,8 @@ func (provider *Mesos) loadMesosConfig() *types.Configuration {
 		"getFrontendBackend": provider.getFrontendBackend,
 		"getID":              provider.getID,
 		"getFrontEndName":    provider.getFrontEndName,
+		"getRateLimit":       provider.getRateLimit,
+		"getLoadBalancer":    provider.getLoadBalancer,
 		"replace":            replace,
 	}
 
@@ -356,6 +358,27 @@ func (provider *Mesos) getFrontendRule(task state.Task) string {
 	return "Host:" + strings.ToLower(strings.Replace(provider.getSubDomain(task.DiscoveryInfo.Name), "_", "-", -1)) + "." + provider.Domain
 }
 
+// getRateLimit returns the frontend rate limiting configuration defined by the
+// task labels, or nil if there is none.
+func (provider *Mesos) getRateLimit(task state.Task) *types.RateLimit {
+	return getRateLimit(taskLabels(task))
+}
+
+// getLoadBalancer returns the backend load balancing configuration defined by
+// the task labels, or nil if there is none.
+func (provider *Mesos) getLoadBalancer(task state.Task) *types.LoadBalancer {
+	return getLoadBalancer(taskLabels(task))
+}
+
+// taskLabels returns the labels of a task as a map.
+func taskLabels(task state.Task) map[string]string {
+	labels := make(map[string]string)
+	for _, label := range task.Labels {
+		labels[label.Key] = label.Value
+	}
+	return labels
+}
+
 func (provider *Mesos) getBackend(task state.Task, applications []state.Task) string {
 	application, errApp := getMesos(task, applications)
 	if errApp != nil {
@@ -246,6 +246,41 @@ func TestMesosLoadConfig(t *testing.T) {
 
 // test helpers
 
+func TestMesosGetLoadBalancer(t *testing.T) {
+	provider := &Mesos{}
+	cases := []struct {
+		task     state.Task
+		expected *types.LoadBalancer
+	}{
+		{
+			task:     task(),
+			expected: nil,
+		},
+		{
+			task:     task(setLabels("traefik.backend.loadbalancer.sticky", "true")),
+			expected: &types.LoadBalancer{Method: "wrr", Sticky: true},
+		},
+		{
+			task: task(setLabels(
+				"traefik.backend.loadbalancer.method", "consistenthash",
+				"traefik.backend.loadbalancer.hashKey", "request.cookie.session",
+			)),
+			expected: &types.LoadBalancer{Method: "consistenthash", HashKey: "request.cookie.session"},
+		},
+		{
+			task:     task(setLabels("traefik.backend.loadbalancer.slowStart", "30s")),
+			expected: &types.LoadBalancer{Method: "wrr", SlowStart: "30s"},
+		},
+	}
+
+	for _, c := range cases {
+		actual := provider.getLoadBalancer(c.task)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Fatalf("expected %+v, got %+v", c.expected, actual)
+		}
+	}
+}
+
 type (
 	taskOpt   func(*state.Task)
 	statusOpt func(*state.Status)
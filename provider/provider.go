@@ -2,19 +2,21 @@ package provider
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"strconv"
 	"strings"
 	"text/template"
 	"unicode"
 
-	"crypto/tls"
-	"crypto/x509"
-	"fmt"
 	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
 	"github.com/containous/traefik/autogen"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
-	"os"
 )
 
 // Provider defines methods of a provider.
@@ -89,6 +91,332 @@ func replace(s1 string, s2 string, s3 string) string {
 	return strings.Replace(s3, s1, s2, -1)
 }
 
+// escape escapes a string to be written in a TOML basic string, between
+// double quotes.
+func escape(s string) string {
+	var buffer bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buffer.WriteRune('\\')
+			buffer.WriteRune(r)
+		case '\n':
+			buffer.WriteString(`\n`)
+		case '\r':
+			buffer.WriteString(`\r`)
+		case '\t':
+			buffer.WriteString(`\t`)
+		default:
+			if unicode.IsControl(r) {
+				fmt.Fprintf(&buffer, `\u%04X`, r)
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+	return buffer.String()
+}
+
+// getLoadBalancer returns the backend load balancing configuration defined by
+// the traefik.backend.loadbalancer.method|sticky|hashKey|slowStart labels,
+// or nil if none is defined.
+func getLoadBalancer(labels map[string]string) *types.LoadBalancer {
+	method, hasMethod := labels["traefik.backend.loadbalancer.method"]
+	sticky, hasSticky := labels["traefik.backend.loadbalancer.sticky"]
+	slowStart, hasSlowStart := labels["traefik.backend.loadbalancer.slowStart"]
+	if !hasMethod && !hasSticky && !hasSlowStart {
+		return nil
+	}
+	loadBalancer := &types.LoadBalancer{
+		Method:    method,
+		HashKey:   labels["traefik.backend.loadbalancer.hashKey"],
+		SlowStart: slowStart,
+	}
+	if len(loadBalancer.Method) == 0 {
+		loadBalancer.Method = "wrr"
+	}
+	if hasSticky {
+		loadBalancer.Sticky = parseSticky(sticky)
+	}
+	return loadBalancer
+}
+
+// parseSticky parses the traefik.backend.loadbalancer.sticky label, an
+// invalid value disabling sticky sessions.
+func parseSticky(label string) bool {
+	sticky, err := strconv.ParseBool(label)
+	if err != nil {
+		log.Errorf("Invalid value for label traefik.backend.loadbalancer.sticky: %v", err)
+		return false
+	}
+	return sticky
+}
+
+const rateLimitLabelPrefix = "traefik.frontend.rateLimit."
+
+// getRateLimit returns the frontend rate limiting configuration defined by
+// labels such as traefik.frontend.rateLimit.extractorFunc and
+// traefik.frontend.rateLimit.rateSet.<name>.period|average|burst,
+// or nil if no rate is defined. Rates with an invalid value or without a
+// positive average are skipped.
+func getRateLimit(labels map[string]string) *types.RateLimit {
+	rateSet := make(map[string]*types.Rate)
+	invalidRates := make(map[string]bool)
+	for key, value := range labels {
+		if !strings.HasPrefix(key, rateLimitLabelPrefix+"rateSet.") {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(key, rateLimitLabelPrefix+"rateSet."), ".")
+		if len(parts) != 2 {
+			log.Errorf("Invalid rate limit label %s", key)
+			continue
+		}
+		rate, exists := rateSet[parts[0]]
+		if !exists {
+			rate = &types.Rate{}
+			rateSet[parts[0]] = rate
+		}
+		var err error
+		switch parts[1] {
+		case "period":
+			rate.Period = value
+		case "average":
+			rate.Average, err = strconv.ParseInt(value, 10, 64)
+		case "burst":
+			rate.Burst, err = strconv.ParseInt(value, 10, 64)
+		default:
+			log.Errorf("Invalid rate limit label %s", key)
+		}
+		if err != nil {
+			log.Errorf("Invalid value for rate limit label %s: %v", key, err)
+			invalidRates[parts[0]] = true
+		}
+	}
+	for name, rate := range rateSet {
+		if invalidRates[name] {
+			log.Errorf("Skipping rate %s: invalid value", name)
+			delete(rateSet, name)
+		} else if rate.Average <= 0 {
+			log.Errorf("Skipping rate %s: average must be greater than 0", name)
+			delete(rateSet, name)
+		}
+	}
+	if len(rateSet) == 0 {
+		return nil
+	}
+	return &types.RateLimit{
+		RateSet:       rateSet,
+		ExtractorFunc: labels[rateLimitLabelPrefix+"extractorFunc"],
+	}
+}
+
+// getMirror returns the frontend traffic mirroring configuration defined by
+// the traefik.frontend.mirror.backend|percent|maxBodySize labels, or nil if
+// no mirror backend is defined.
+func getMirror(labels map[string]string) *types.Mirror {
+	backend, ok := labels["traefik.frontend.mirror.backend"]
+	if !ok || len(backend) == 0 {
+		return nil
+	}
+	mirror := &types.Mirror{Backend: backend}
+	if label, ok := labels["traefik.frontend.mirror.percent"]; ok {
+		if percent, err := strconv.Atoi(label); err != nil {
+			log.Errorf("Invalid value for label traefik.frontend.mirror.percent: %v", err)
+		} else {
+			mirror.Percent = &percent
+		}
+	}
+	if maxBodySize, ok := labels["traefik.frontend.mirror.maxBodySize"]; ok {
+		var err error
+		if mirror.MaxBodySize, err = strconv.ParseInt(maxBodySize, 10, 64); err != nil {
+			log.Errorf("Invalid value for label traefik.frontend.mirror.maxBodySize: %v", err)
+		}
+	}
+	return mirror
+}
+
+// getSplit returns the weighted traffic split of a frontend shared by several
+// backends, given the labels of each backend. The weight of a backend is
+// defined by the traefik.frontend.split.weight label (1 by default), and the
+// split is sticky if traefik.frontend.split.sticky is set on one of them.
+// It returns nil if there is a single backend or no split weight is defined.
+func getSplit(backendLabels map[string]map[string]string) *types.Split {
+	if len(backendLabels) < 2 {
+		return nil
+	}
+	split := &types.Split{Backends: make(map[string]int)}
+	weighted := false
+	for backendName, labels := range backendLabels {
+		split.Backends[backendName] = 1
+		if weight, ok := labels["traefik.frontend.split.weight"]; ok {
+			weighted = true
+			var err error
+			if split.Backends[backendName], err = strconv.Atoi(weight); err != nil {
+				log.Errorf("Invalid value for label traefik.frontend.split.weight: %v", err)
+				split.Backends[backendName] = 1
+			}
+		}
+		if sticky, ok := labels["traefik.frontend.split.sticky"]; ok {
+			if isSticky, err := strconv.ParseBool(sticky); err != nil {
+				log.Errorf("Invalid value for label traefik.frontend.split.sticky: %v", err)
+			} else if isSticky {
+				split.Sticky = true
+			}
+		}
+	}
+	if !weighted {
+		return nil
+	}
+	return split
+}
+
+const errorPagesLabelPrefix = "traefik.frontend.errors."
+
+// getErrorPages returns the frontend error pages defined by labels such as
+// traefik.frontend.errors.<name>.status|backend|query, or nil if none is
+// defined. Status is a comma separated list of codes or ranges.
+func getErrorPages(labels map[string]string) map[string]*types.ErrorPage {
+	errorPages := make(map[string]*types.ErrorPage)
+	for key, value := range labels {
+		if !strings.HasPrefix(key, errorPagesLabelPrefix) {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(key, errorPagesLabelPrefix), ".")
+		if len(parts) != 2 {
+			log.Errorf("Invalid error page label %s", key)
+			continue
+		}
+		errorPage, exists := errorPages[parts[0]]
+		if !exists {
+			errorPage = &types.ErrorPage{}
+			errorPages[parts[0]] = errorPage
+		}
+		switch parts[1] {
+		case "status":
+			errorPage.Status = splitLabelList(value)
+		case "backend":
+			errorPage.Backend = value
+		case "query":
+			errorPage.Query = value
+		default:
+			log.Errorf("Invalid error page label %s", key)
+		}
+	}
+	if len(errorPages) == 0 {
+		return nil
+	}
+	return errorPages
+}
+
+const headersLabelPrefix = "traefik.frontend.headers."
+
+// getHeaders returns the frontend headers configuration defined by labels
+// such as traefik.frontend.headers.frameDeny, or nil if none is defined.
+// Custom headers are given as Name:value pairs separated by ||, an empty
+// value removing the header.
+func getHeaders(labels map[string]string) *types.Headers {
+	headers := &types.Headers{}
+	defined := false
+	for key, value := range labels {
+		if !strings.HasPrefix(key, headersLabelPrefix) {
+			continue
+		}
+		defined = true
+		var err error
+		switch name := strings.TrimPrefix(key, headersLabelPrefix); name {
+		case "customRequestHeaders":
+			headers.CustomRequestHeaders = parseCustomHeaders(value)
+		case "customResponseHeaders":
+			headers.CustomResponseHeaders = parseCustomHeaders(value)
+		case "allowedHosts":
+			headers.AllowedHosts = strings.Split(value, ",")
+		case "SSLRedirect":
+			headers.SSLRedirect, err = strconv.ParseBool(value)
+		case "SSLHost":
+			headers.SSLHost = value
+		case "STSSeconds":
+			headers.STSSeconds, err = strconv.ParseInt(value, 10, 64)
+		case "STSIncludeSubdomains":
+			headers.STSIncludeSubdomains, err = strconv.ParseBool(value)
+		case "STSPreload":
+			headers.STSPreload, err = strconv.ParseBool(value)
+		case "frameDeny":
+			headers.FrameDeny, err = strconv.ParseBool(value)
+		case "customFrameOptionsValue":
+			headers.CustomFrameOptionsValue = value
+		case "contentTypeNosniff":
+			headers.ContentTypeNosniff, err = strconv.ParseBool(value)
+		case "browserXSSFilter":
+			headers.BrowserXSSFilter, err = strconv.ParseBool(value)
+		case "contentSecurityPolicy":
+			headers.ContentSecurityPolicy = value
+		default:
+			log.Errorf("Invalid headers label %s", key)
+		}
+		if err != nil {
+			log.Errorf("Invalid value for headers label %s: %v", key, err)
+		}
+	}
+	if !defined {
+		return nil
+	}
+	return headers
+}
+
+const corsLabelPrefix = "traefik.frontend.cors."
+
+// getCORS returns the frontend CORS configuration defined by labels such as
+// traefik.frontend.cors.allowedOrigins, or nil if no origin is allowed.
+func getCORS(labels map[string]string) *types.CORS {
+	allowedOrigins, exists := labels[corsLabelPrefix+"allowedOrigins"]
+	if !exists {
+		return nil
+	}
+	cors := &types.CORS{
+		AllowedOrigins: splitLabelList(allowedOrigins),
+		AllowedMethods: splitLabelList(labels[corsLabelPrefix+"allowedMethods"]),
+		AllowedHeaders: splitLabelList(labels[corsLabelPrefix+"allowedHeaders"]),
+		ExposedHeaders: splitLabelList(labels[corsLabelPrefix+"exposedHeaders"]),
+	}
+	var err error
+	if value, exists := labels[corsLabelPrefix+"allowCredentials"]; exists {
+		if cors.AllowCredentials, err = strconv.ParseBool(value); err != nil {
+			log.Errorf("Invalid value for CORS label %sallowCredentials: %v", corsLabelPrefix, err)
+		}
+	}
+	if value, exists := labels[corsLabelPrefix+"maxAge"]; exists {
+		if cors.MaxAge, err = strconv.Atoi(value); err != nil {
+			log.Errorf("Invalid value for CORS label %smaxAge: %v", corsLabelPrefix, err)
+		}
+	}
+	return cors
+}
+
+// splitLabelList splits a comma separated label value, ignoring empty items.
+func splitLabelList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseCustomHeaders(value string) map[string]string {
+	customHeaders := make(map[string]string)
+	for _, header := range strings.Split(value, "||") {
+		parts := strings.SplitN(header, ":", 2)
+		if name := strings.TrimSpace(parts[0]); len(name) > 0 {
+			customHeaders[name] = ""
+			if len(parts) == 2 {
+				customHeaders[name] = strings.TrimSpace(parts[1])
+			}
+		}
+	}
+	return customHeaders
+}
+
 func normalize(name string) string {
 	fargs := func(c rune) bool {
 		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
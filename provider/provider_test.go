@@ -7,6 +7,7 @@ import (
 	"testing"
 	"text/template"
 
+	"github.com/BurntSushi/toml"
 	"github.com/containous/traefik/types"
 )
 
@@ -171,6 +172,27 @@ func TestReplace(t *testing.T) {
 	}
 }
 
+func TestEscape(t *testing.T) {
+	cases := []string{
+		"",
+		"default-src 'self'",
+		`script-src "nonce-abc" https:`,
+		`C:\path\`,
+		"line\nbreak\ttab\x00",
+		"π ✓",
+	}
+
+	for _, c := range cases {
+		var actual struct{ Value string }
+		if _, err := toml.Decode(`value = "`+escape(c)+`"`, &actual); err != nil {
+			t.Fatalf("error decoding escaped %q: %v", c, err)
+		}
+		if actual.Value != c {
+			t.Fatalf("expected %q, got %q", c, actual.Value)
+		}
+	}
+}
+
 func TestGetConfigurationReturnsCorrectMaxConnConfiguration(t *testing.T) {
 	templateFile, err := ioutil.TempFile("", "provider-configuration")
 	if err != nil {
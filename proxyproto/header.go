@@ -0,0 +1,250 @@
+// Package proxyproto reads and writes the PROXY protocol headers, version 1
+// and 2, sent by load balancers in front of the connections they proxy to
+// carry the addresses of the clients.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxV1HeaderSize is the maximum size of a version 1 header, with its
+	// CRLF.
+	maxV1HeaderSize = 107
+	v1Prefix        = "PROXY "
+
+	v2Version       = 0x20
+	v2CommandLocal  = 0x00
+	v2CommandProxy  = 0x01
+	v2FamilyUnspec  = 0x00
+	v2FamilyTCPv4   = 0x11
+	v2FamilyTCPv6   = 0x21
+	v2AddressesIPv4 = 12
+	v2AddressesIPv6 = 36
+)
+
+// v2Signature is the first 12 bytes of a version 2 header.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrNoHeader is returned when a connection doesn't start with a PROXY
+// protocol header.
+var ErrNoHeader = errors.New("No PROXY protocol header")
+
+// Header is a PROXY protocol header. Its addresses are nil when unknown, like
+// for the health checks of the load balancers.
+type Header struct {
+	Version     int
+	Source      *net.TCPAddr
+	Destination *net.TCPAddr
+}
+
+// NewHeader returns a header of the given version for a connection from
+// source to destination, which are ignored if they're not TCP addresses.
+func NewHeader(version int, source, destination net.Addr) (*Header, error) {
+	if err := CheckVersion(version); err != nil {
+		return nil, err
+	}
+	header := &Header{Version: version}
+	sourceAddr, sourceOk := source.(*net.TCPAddr)
+	destinationAddr, destinationOk := destination.(*net.TCPAddr)
+	if sourceOk && destinationOk {
+		header.Source = sourceAddr
+		header.Destination = destinationAddr
+	}
+	return header, nil
+}
+
+// CheckVersion returns an error if version is not a PROXY protocol version.
+func CheckVersion(version int) error {
+	if version != 1 && version != 2 {
+		return fmt.Errorf("Unsupported PROXY protocol version %d", version)
+	}
+	return nil
+}
+
+// ReadHeader reads the PROXY protocol header at the beginning of reader.
+func ReadHeader(reader *bufio.Reader) (*Header, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case v1Prefix[0]:
+		return readV1Header(reader)
+	case v2Signature[0]:
+		return readV2Header(reader)
+	}
+	return nil, ErrNoHeader
+}
+
+func readV1Header(reader *bufio.Reader) (*Header, error) {
+	var line []byte
+	for len(line) < maxV1HeaderSize {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("Invalid PROXY protocol header: missing CRLF")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if fields[0] != strings.TrimSpace(v1Prefix) || len(fields) < 2 {
+		return nil, ErrNoHeader
+	}
+	header := &Header{Version: 1}
+	switch fields[1] {
+	case "UNKNOWN":
+		return header, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("Invalid PROXY protocol header: unsupported protocol %s", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("Invalid PROXY protocol header: %q", line)
+	}
+	source, err := parseV1Address(fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+	destination, err := parseV1Address(fields[3], fields[5])
+	if err != nil {
+		return nil, err
+	}
+	header.Source = source
+	header.Destination = destination
+	return header, nil
+}
+
+func parseV1Address(ip, port string) (*net.TCPAddr, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	if addr.IP == nil {
+		return nil, fmt.Errorf("Invalid PROXY protocol header: bad address %s", ip)
+	}
+	var err error
+	addr.Port, err = strconv.Atoi(port)
+	if err != nil || addr.Port < 0 || addr.Port > 65535 {
+		return nil, fmt.Errorf("Invalid PROXY protocol header: bad port %s", port)
+	}
+	return addr, nil
+}
+
+func readV2Header(reader *bufio.Reader) (*Header, error) {
+	prefix := make([]byte, len(v2Signature)+4)
+	if _, err := io.ReadFull(reader, prefix); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prefix[:len(v2Signature)], v2Signature) {
+		return nil, ErrNoHeader
+	}
+	versionCommand := prefix[12]
+	family := prefix[13]
+	length := int(binary.BigEndian.Uint16(prefix[14:]))
+	if versionCommand&0xF0 != v2Version {
+		return nil, fmt.Errorf("Invalid PROXY protocol header: unsupported version %d", versionCommand>>4)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	header := &Header{Version: 2}
+	switch versionCommand & 0x0F {
+	case v2CommandLocal:
+		return header, nil
+	case v2CommandProxy:
+	default:
+		return nil, fmt.Errorf("Invalid PROXY protocol header: unsupported command %d", versionCommand&0x0F)
+	}
+	switch family {
+	case v2FamilyTCPv4:
+		if length < v2AddressesIPv4 {
+			return nil, errors.New("Invalid PROXY protocol header: truncated addresses")
+		}
+		header.Source = &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:]))}
+		header.Destination = &net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:]))}
+	case v2FamilyTCPv6:
+		if length < v2AddressesIPv6 {
+			return nil, errors.New("Invalid PROXY protocol header: truncated addresses")
+		}
+		header.Source = &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:]))}
+		header.Destination = &net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:]))}
+	}
+	// other families, like UDP or unix sockets, keep the connection addresses
+	return header, nil
+}
+
+// Bytes returns the header in the format of its version.
+func (h *Header) Bytes() []byte {
+	if h.Version == 2 {
+		return h.v2Bytes()
+	}
+	return h.v1Bytes()
+}
+
+// WriteTo writes the header to w.
+func (h *Header) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(h.Bytes())
+	return int64(n), err
+}
+
+func (h *Header) v1Bytes() []byte {
+	if h.Source == nil || h.Destination == nil {
+		return []byte(v1Prefix + "UNKNOWN\r\n")
+	}
+	sourceIP, destinationIP := h.ips()
+	if len(sourceIP) == net.IPv4len {
+		return []byte(fmt.Sprintf("%sTCP4 %s %s %d %d\r\n", v1Prefix, sourceIP, destinationIP, h.Source.Port, h.Destination.Port))
+	}
+	return []byte(fmt.Sprintf("%sTCP6 %s %s %d %d\r\n", v1Prefix, formatIPv6(sourceIP), formatIPv6(destinationIP), h.Source.Port, h.Destination.Port))
+}
+
+// formatIPv6 formats an IP as an IPv6 address, the IPv4 ones being mapped.
+func formatIPv6(ip net.IP) string {
+	if ip.To4() != nil {
+		return "::ffff:" + ip.To4().String()
+	}
+	return ip.String()
+}
+
+func (h *Header) v2Bytes() []byte {
+	buffer := bytes.NewBuffer(nil)
+	buffer.Write(v2Signature)
+	if h.Source == nil || h.Destination == nil {
+		buffer.Write([]byte{v2Version | v2CommandLocal, v2FamilyUnspec, 0, 0})
+		return buffer.Bytes()
+	}
+	sourceIP, destinationIP := h.ips()
+	family := byte(v2FamilyTCPv6)
+	if len(sourceIP) == net.IPv4len {
+		family = v2FamilyTCPv4
+	}
+	buffer.Write([]byte{v2Version | v2CommandProxy, family})
+	binary.Write(buffer, binary.BigEndian, uint16(2*len(sourceIP)+4))
+	buffer.Write(sourceIP)
+	buffer.Write(destinationIP)
+	binary.Write(buffer, binary.BigEndian, uint16(h.Source.Port))
+	binary.Write(buffer, binary.BigEndian, uint16(h.Destination.Port))
+	return buffer.Bytes()
+}
+
+// ips returns the IPs of the addresses with the same length, IPv4 if both of
+// them are IPv4 addresses.
+func (h *Header) ips() (net.IP, net.IP) {
+	sourceIP, destinationIP := h.Source.IP.To4(), h.Destination.IP.To4()
+	if sourceIP == nil || destinationIP == nil {
+		return h.Source.IP.To16(), h.Destination.IP.To16()
+	}
+	return sourceIP, destinationIP
+}
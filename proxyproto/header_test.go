@@ -0,0 +1,132 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadHeader(t *testing.T) {
+	cases := []struct {
+		desc        string
+		data        string
+		source      string
+		destination string
+		err         bool
+	}{
+		{
+			desc:        "v1 TCP4",
+			data:        "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nGET / HTTP/1.1\r\n",
+			source:      "192.168.0.1:56324",
+			destination: "192.168.0.11:443",
+		},
+		{
+			desc:        "v1 TCP6",
+			data:        "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n",
+			source:      "[2001:db8::1]:56324",
+			destination: "[2001:db8::2]:443",
+		},
+		{
+			desc: "v1 UNKNOWN",
+			data: "PROXY UNKNOWN\r\n",
+		},
+		{
+			desc: "v1 without CRLF",
+			data: "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\n",
+			err:  true,
+		},
+		{
+			desc: "v1 bad address",
+			data: "PROXY TCP4 foo 192.168.0.11 56324 443\r\n",
+			err:  true,
+		},
+		{
+			desc: "v1 too long",
+			data: "PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n",
+			err:  true,
+		},
+		{
+			desc:        "v2 TCP4",
+			data:        "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c" + "\xc0\xa8\x00\x01" + "\xc0\xa8\x00\x0b" + "\xdc\x04" + "\x01\xbb",
+			source:      "192.168.0.1:56324",
+			destination: "192.168.0.11:443",
+		},
+		{
+			desc: "v2 LOCAL",
+			data: "\r\n\r\n\x00\r\nQUIT\n\x20\x00\x00\x00",
+		},
+		{
+			desc: "v2 truncated",
+			data: "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c\xc0\xa8",
+			err:  true,
+		},
+		{
+			desc: "no header",
+			data: "GET / HTTP/1.1\r\n",
+			err:  true,
+		},
+	}
+
+	for _, c := range cases {
+		header, err := ReadHeader(bufio.NewReader(strings.NewReader(c.data)))
+		if c.err {
+			if err == nil {
+				t.Fatalf("%s: expected an error, got %+v", c.desc, header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", c.desc, err)
+		}
+		if len(c.source) == 0 {
+			if header.Source != nil || header.Destination != nil {
+				t.Fatalf("%s: expected unknown addresses, got %s and %s", c.desc, header.Source, header.Destination)
+			}
+			continue
+		}
+		if header.Source.String() != c.source || header.Destination.String() != c.destination {
+			t.Fatalf("%s: expected %s and %s, got %s and %s", c.desc, c.source, c.destination, header.Source, header.Destination)
+		}
+	}
+}
+
+func TestHeaderBytes(t *testing.T) {
+	source := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	destination := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+	source6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324}
+
+	header, _ := NewHeader(1, source, destination)
+	if string(header.Bytes()) != "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n" {
+		t.Fatalf("Unexpected v1 header %q", header.Bytes())
+	}
+	header, _ = NewHeader(1, source6, destination)
+	if string(header.Bytes()) != "PROXY TCP6 2001:db8::1 ::ffff:192.168.0.11 56324 443\r\n" {
+		t.Fatalf("Unexpected v1 header %q", header.Bytes())
+	}
+	header, _ = NewHeader(1, nil, nil)
+	if string(header.Bytes()) != "PROXY UNKNOWN\r\n" {
+		t.Fatalf("Unexpected v1 header %q", header.Bytes())
+	}
+	header, _ = NewHeader(2, nil, nil)
+	if !bytes.Equal(header.Bytes(), []byte("\r\n\r\n\x00\r\nQUIT\n\x20\x00\x00\x00")) {
+		t.Fatalf("Unexpected v2 header %q", header.Bytes())
+	}
+	if _, err := NewHeader(3, source, destination); err == nil {
+		t.Fatalf("Version 3 should return an error")
+	}
+
+	for _, version := range []int{1, 2} {
+		for _, addrs := range [][2]*net.TCPAddr{{source, destination}, {source6, destination}} {
+			header, _ := NewHeader(version, addrs[0], addrs[1])
+			read, err := ReadHeader(bufio.NewReader(bytes.NewReader(header.Bytes())))
+			if err != nil {
+				t.Fatalf("Error reading v%d header %q: %v", version, header.Bytes(), err)
+			}
+			if !read.Source.IP.Equal(addrs[0].IP) || read.Source.Port != addrs[0].Port || !read.Destination.IP.Equal(addrs[1].IP) || read.Destination.Port != addrs[1].Port {
+				t.Fatalf("Expected %s and %s from v%d header, got %s and %s", addrs[0], addrs[1], version, read.Source, read.Destination)
+			}
+		}
+	}
+}
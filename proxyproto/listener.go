@@ -0,0 +1,193 @@
+package proxyproto
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/safe"
+)
+
+// DefaultHeaderTimeout is the default maximum time waiting for the PROXY
+// protocol header of a connection.
+const DefaultHeaderTimeout = 10 * time.Second
+
+// Listener reads the PROXY protocol header of the connections accepted from
+// trusted sources, their addresses becoming the ones of the header. The
+// connections from other sources are accepted as they are.
+// The headers are read before returning the connections from Accept, without
+// blocking the connections accepted meanwhile.
+type Listener struct {
+	net.Listener
+	HeaderTimeout time.Duration
+	trustedIPs    []*net.IPNet
+	conns         chan net.Conn
+	errs          chan error
+	done          chan bool
+	closeOnce     sync.Once
+	startOnce     sync.Once
+}
+
+// NewListener returns a new Listener over listener, trusting the headers of
+// the connections from trustedIPs, a list of IPs or CIDRs.
+func NewListener(listener net.Listener, trustedIPs []string) (*Listener, error) {
+	l := &Listener{
+		Listener:      listener,
+		HeaderTimeout: DefaultHeaderTimeout,
+		conns:         make(chan net.Conn),
+		errs:          make(chan error),
+		done:          make(chan bool),
+	}
+	for _, trustedIP := range trustedIPs {
+		if !strings.Contains(trustedIP, "/") {
+			if ip := net.ParseIP(trustedIP); ip != nil && ip.To4() != nil {
+				trustedIP += "/32"
+			} else {
+				trustedIP += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(trustedIP)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trusted IP %s: %v", trustedIP, err)
+		}
+		l.trustedIPs = append(l.trustedIPs, ipNet)
+	}
+	return l, nil
+}
+
+// Accept returns the next connection, once its header has been read.
+func (l *Listener) Accept() (net.Conn, error) {
+	l.startOnce.Do(func() {
+		safe.Go(l.acceptLoop)
+	})
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, fmt.Errorf("Listener on %s closed", l.Addr())
+	}
+}
+
+// Close closes the listener and drops the connections whose header is being
+// read.
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	return l.Listener.Close()
+}
+
+func (l *Listener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.errs <- err:
+			case <-l.done:
+				return
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				continue
+			}
+			return
+		}
+		safe.Go(func() {
+			l.handshake(conn)
+		})
+	}
+}
+
+// handshake reads the header of a connection from a trusted source, and
+// hands the connection to Accept.
+func (l *Listener) handshake(conn net.Conn) {
+	if l.isTrusted(conn.RemoteAddr()) {
+		proxyConn, err := l.readHeader(conn)
+		if err != nil {
+			log.Debugf("Error reading PROXY protocol header from %s: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+		conn = proxyConn
+	}
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+func (l *Listener) readHeader(conn net.Conn) (net.Conn, error) {
+	conn.SetReadDeadline(time.Now().Add(l.HeaderTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+	reader := bufio.NewReader(conn)
+	header, err := ReadHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, reader: reader, header: header}, nil
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, ipNet := range l.trustedIPs {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a connection whose addresses are the ones of its PROXY protocol
+// header, when known.
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+	header *Header
+}
+
+// Header returns the PROXY protocol header of the connection.
+func (c *Conn) Header() *Header {
+	return c.header
+}
+
+// Read reads the data following the header.
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the source address of the header, or the one of the
+// connection if unknown.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.header.Source != nil {
+		return c.header.Source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address of the header, or the one of the
+// connection if unknown.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.header.Destination != nil {
+		return c.header.Destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+// CloseWrite closes the connection for writing, or closes it if it can't.
+func (c *Conn) CloseWrite() error {
+	if conn, ok := c.Conn.(interface {
+		CloseWrite() error
+	}); ok {
+		return conn.CloseWrite()
+	}
+	return c.Conn.Close()
+}
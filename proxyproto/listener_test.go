@@ -0,0 +1,98 @@
+package proxyproto
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// startListener returns a Listener and the channel of the remote address and
+// data of each of its connections.
+func startListener(t *testing.T, trustedIPs []string) (*Listener, chan string) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	listener, err := NewListener(tcpListener, trustedIPs)
+	if err != nil {
+		t.Fatalf("Error creating listener: %v", err)
+	}
+	listener.HeaderTimeout = 100 * time.Millisecond
+	received := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			data, _ := ioutil.ReadAll(conn)
+			received <- conn.RemoteAddr().String() + " " + string(data)
+			conn.Close()
+		}
+	}()
+	return listener, received
+}
+
+func send(t *testing.T, address string, data string) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("Error connecting: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(data))
+}
+
+func receive(t *testing.T, received chan string) string {
+	select {
+	case data := <-received:
+		return data
+	case <-time.After(time.Second):
+		t.Fatalf("No connection received")
+	}
+	return ""
+}
+
+func TestListenerTrusted(t *testing.T) {
+	listener, received := startListener(t, []string{"127.0.0.1"})
+	defer listener.Close()
+
+	send(t, listener.Addr().String(), "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nhello")
+	if data := receive(t, received); data != "192.168.0.1:56324 hello" {
+		t.Fatalf("Unexpected connection %q", data)
+	}
+
+	// a connection without header is dropped, without blocking the others
+	stalled, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting: %v", err)
+	}
+	defer stalled.Close()
+	send(t, listener.Addr().String(), "\r\n\r\n\x00\r\nQUIT\n\x20\x00\x00\x00hello")
+	if data := receive(t, received); data[len(data)-6:] != " hello" {
+		t.Fatalf("Unexpected connection %q", data)
+	}
+	send(t, listener.Addr().String(), "hello")
+	select {
+	case data := <-received:
+		t.Fatalf("Connection without header should be dropped, got %q", data)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestListenerUntrusted(t *testing.T) {
+	listener, received := startListener(t, []string{"10.0.0.0/8"})
+	defer listener.Close()
+
+	send(t, listener.Addr().String(), "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n")
+	data := receive(t, received)
+	if data[:10] != "127.0.0.1:" || data[len(data)-2:] != "\r\n" {
+		t.Fatalf("Header from untrusted source should be kept, got %q", data)
+	}
+}
+
+func TestListenerInvalidTrustedIP(t *testing.T) {
+	if _, err := NewListener(nil, []string{"foo"}); err == nil {
+		t.Fatalf("Invalid trusted IP should return an error")
+	}
+}
@@ -2,22 +2,27 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/containous/mux"
 	"net"
 	"net/http"
-	"reflect"
 	"sort"
 	"strings"
+	"unicode"
 )
 
 // Rules holds rule parsing and configuration
 type Rules struct {
 	route *serverRoute
-	err   error
 }
 
-func (r *Rules) host(hosts ...string) *mux.Route {
-	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+// matcher reports whether a request matches a rule expression.
+// Route variables and handlers (StrictSlash redirects) found by mux are only
+// written to match when the matcher returns true.
+type matcher func(req *http.Request, match *mux.RouteMatch) bool
+
+func (r *Rules) host(hosts ...string) (matcher, error) {
+	return func(req *http.Request, match *mux.RouteMatch) bool {
 		reqHost, _, err := net.SplitHostPort(req.Host)
 		if err != nil {
 			reqHost = req.Host
@@ -28,31 +33,19 @@ func (r *Rules) host(hosts ...string) *mux.Route {
 			}
 		}
 		return false
-	})
+	}, nil
 }
 
-func (r *Rules) hostRegexp(hosts ...string) *mux.Route {
-	router := r.route.route.Subrouter()
-	for _, host := range hosts {
-		router.Host(strings.TrimSpace(host))
-	}
-	return r.route.route
+func (r *Rules) hostRegexp(hosts ...string) (matcher, error) {
+	return r.anyRoute(hosts, (*mux.Route).Host)
 }
 
-func (r *Rules) path(paths ...string) *mux.Route {
-	router := r.route.route.Subrouter()
-	for _, path := range paths {
-		router.Path(strings.TrimSpace(path))
-	}
-	return r.route.route
+func (r *Rules) path(paths ...string) (matcher, error) {
+	return r.anyRoute(paths, (*mux.Route).Path)
 }
 
-func (r *Rules) pathPrefix(paths ...string) *mux.Route {
-	router := r.route.route.Subrouter()
-	for _, path := range paths {
-		router.PathPrefix(strings.TrimSpace(path))
-	}
-	return r.route.route
+func (r *Rules) pathPrefix(paths ...string) (matcher, error) {
+	return r.anyRoute(paths, (*mux.Route).PathPrefix)
 }
 
 type bySize []string
@@ -61,106 +54,354 @@ func (a bySize) Len() int           { return len(a) }
 func (a bySize) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a bySize) Less(i, j int) bool { return len(a[i]) > len(a[j]) }
 
-func (r *Rules) pathStrip(paths ...string) *mux.Route {
-	sort.Sort(bySize(paths))
-	r.route.stripPrefixes = paths
-	router := r.route.route.Subrouter()
-	for _, path := range paths {
-		router.Path(strings.TrimSpace(path))
+func (r *Rules) pathStrip(paths ...string) (matcher, error) {
+	r.addStripPrefixes(paths)
+	return r.anyRoute(paths, (*mux.Route).Path)
+}
+
+func (r *Rules) pathPrefixStrip(paths ...string) (matcher, error) {
+	r.addStripPrefixes(paths)
+	return r.anyRoute(paths, (*mux.Route).PathPrefix)
+}
+
+func (r *Rules) addStripPrefixes(paths []string) {
+	r.route.stripPrefixes = append(r.route.stripPrefixes, paths...)
+	sort.Stable(bySize(r.route.stripPrefixes))
+}
+
+func (r *Rules) methods(methods ...string) (matcher, error) {
+	return routeMatcher(r.newRoute().Methods(methods...))
+}
+
+func (r *Rules) headers(headers ...string) (matcher, error) {
+	return routeMatcher(r.newRoute().Headers(headers...))
+}
+
+func (r *Rules) headersRegexp(headers ...string) (matcher, error) {
+	return routeMatcher(r.newRoute().HeadersRegexp(headers...))
+}
+
+// newRoute returns a standalone mux route, used to evaluate mux matchers
+// outside of the frontend route.
+func (r *Rules) newRoute() *mux.Route {
+	router := mux.NewRouter()
+	router.StrictSlash(true)
+	return router.NewRoute()
+}
+
+// anyRoute returns a matcher matching if any of the values matches, using the
+// given mux route matcher.
+func (r *Rules) anyRoute(values []string, muxMatcher func(*mux.Route, string) *mux.Route) (matcher, error) {
+	matchers := make([]matcher, 0, len(values))
+	for _, value := range values {
+		m, err := routeMatcher(muxMatcher(r.newRoute(), strings.TrimSpace(value)))
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return orMatcher(matchers...), nil
+}
+
+func routeMatcher(route *mux.Route) (matcher, error) {
+	if err := route.GetError(); err != nil {
+		return nil, err
 	}
-	return r.route.route
+	return func(req *http.Request, match *mux.RouteMatch) bool {
+		routeMatch := &mux.RouteMatch{}
+		if !route.Match(req, routeMatch) {
+			return false
+		}
+		mergeRouteMatch(match, routeMatch)
+		return true
+	}, nil
 }
 
-func (r *Rules) pathPrefixStrip(paths ...string) *mux.Route {
-	sort.Sort(bySize(paths))
-	r.route.stripPrefixes = paths
-	router := r.route.route.Subrouter()
-	for _, path := range paths {
-		router.PathPrefix(strings.TrimSpace(path))
+func mergeRouteMatch(dst, src *mux.RouteMatch) {
+	if dst.Handler == nil && src.Handler != nil {
+		dst.Handler = src.Handler
+	}
+	if len(src.Vars) > 0 && dst.Vars == nil {
+		dst.Vars = make(map[string]string)
+	}
+	for key, value := range src.Vars {
+		dst.Vars[key] = value
 	}
-	return r.route.route
 }
 
-func (r *Rules) methods(methods ...string) *mux.Route {
-	return r.route.route.Methods(methods...)
+func andMatcher(left, right matcher) matcher {
+	return func(req *http.Request, match *mux.RouteMatch) bool {
+		andMatch := &mux.RouteMatch{}
+		if !left(req, andMatch) || !right(req, andMatch) {
+			return false
+		}
+		mergeRouteMatch(match, andMatch)
+		return true
+	}
 }
 
-func (r *Rules) headers(headers ...string) *mux.Route {
-	return r.route.route.Headers(headers...)
+func orMatcher(matchers ...matcher) matcher {
+	return func(req *http.Request, match *mux.RouteMatch) bool {
+		for _, m := range matchers {
+			if m(req, match) {
+				return true
+			}
+		}
+		return false
+	}
 }
 
-func (r *Rules) headersRegexp(headers ...string) *mux.Route {
-	return r.route.route.HeadersRegexp(headers...)
+func notMatcher(m matcher) matcher {
+	return func(req *http.Request, match *mux.RouteMatch) bool {
+		return !m(req, &mux.RouteMatch{})
+	}
 }
 
 // Parse parses rules expressions
+//
+// Matchers are written `Name:arg1,arg2` and can be combined with `&&` (or the
+// legacy `;`), `||`, `!` and parentheses, `!` binding tighter than `&&`, which
+// binds tighter than `||`.
 func (r *Rules) Parse(expression string) (*mux.Route, error) {
-	functions := map[string]interface{}{
-		"Host":            r.host,
-		"HostRegexp":      r.hostRegexp,
-		"Path":            r.path,
-		"PathStrip":       r.pathStrip,
-		"PathPrefix":      r.pathPrefix,
-		"PathPrefixStrip": r.pathPrefixStrip,
-		"Method":          r.methods,
-		"Headers":         r.headers,
-		"HeadersRegexp":   r.headersRegexp,
-	}
-
-	if len(expression) == 0 {
+	if len(strings.TrimSpace(expression)) == 0 {
 		return nil, errors.New("Empty rule")
 	}
-
-	f := func(c rune) bool {
-		return c == ':'
+	parser := &ruleParser{rules: r, expression: expression}
+	m, err := parser.parse()
+	if err != nil {
+		return nil, err
 	}
+	route := r.route.route.MatcherFunc(mux.MatcherFunc(m))
+	if route.GetError() != nil {
+		return nil, route.GetError()
+	}
+	return route, nil
+}
+
+// ruleParser is a recursive descent parser for rule expressions:
+//
+//	or      = and { "||" and }
+//	and     = not { ( "&&" | ";" ) not }
+//	not     = "!" not | primary
+//	primary = "(" or ")" | Name ":" args
+type ruleParser struct {
+	rules      *Rules
+	expression string
+	pos        int
+	// strips counts the PathStrip and PathPrefixStrip matchers parsed so far,
+	// which are rejected under ! and || as their prefixes are stripped
+	// whichever branch matched
+	strips int
+}
 
-	// Allow multiple rules separated by ;
-	splitRule := func(c rune) bool {
-		return c == ';'
+func (p *ruleParser) parse() (matcher, error) {
+	m, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.expression) {
+		return nil, p.errorf("unexpected '%c'", p.expression[p.pos])
 	}
+	return m, nil
+}
 
-	parsedRules := strings.FieldsFunc(expression, splitRule)
+func (p *ruleParser) parseOr() (matcher, error) {
+	strips := p.strips
+	m, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	matchers := []matcher{m}
+	for p.consume("||") {
+		m, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	if len(matchers) == 1 {
+		return matchers[0], nil
+	}
+	if p.strips > strips {
+		return nil, p.errorf("PathStrip and PathPrefixStrip can't be combined with '||'")
+	}
+	return orMatcher(matchers...), nil
+}
 
-	var resultRoute *mux.Route
+func (p *ruleParser) parseAnd() (matcher, error) {
+	m, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.consume(";") {
+			// the legacy syntax ignored empty rules between ;
+			for p.consume(";") {
+			}
+			if p.atEnd() {
+				break
+			}
+		} else if !p.consume("&&") {
+			break
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		m = andMatcher(m, right)
+	}
+	return m, nil
+}
 
-	for _, rule := range parsedRules {
-		// get function
-		parsedFunctions := strings.FieldsFunc(rule, f)
-		if len(parsedFunctions) == 0 {
-			return nil, errors.New("Error parsing rule: '" + rule + "'")
+func (p *ruleParser) parseNot() (matcher, error) {
+	if p.consume("!") {
+		strips := p.strips
+		m, err := p.parseNot()
+		if err != nil {
+			return nil, err
 		}
-		parsedFunction, ok := functions[strings.TrimSpace(parsedFunctions[0])]
-		if !ok {
-			return nil, errors.New("Error parsing rule: '" + rule + "'. Unknown function: '" + parsedFunctions[0] + "'")
+		if p.strips > strips {
+			return nil, p.errorf("PathStrip and PathPrefixStrip can't be negated")
 		}
-		parsedFunctions = append(parsedFunctions[:0], parsedFunctions[1:]...)
-		fargs := func(c rune) bool {
-			return c == ','
+		return notMatcher(m), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *ruleParser) parsePrimary() (matcher, error) {
+	if p.consume("(") {
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
 		}
-		// get function
-		parsedArgs := strings.FieldsFunc(strings.Join(parsedFunctions, ":"), fargs)
-		if len(parsedArgs) == 0 {
-			return nil, errors.New("Error parsing args from rule: '" + rule + "'")
+		if !p.consume(")") {
+			return nil, p.errorf("missing ')'")
 		}
+		return m, nil
+	}
+	return p.parseMatcher()
+}
 
-		inputs := make([]reflect.Value, len(parsedArgs))
-		for i := range parsedArgs {
-			inputs[i] = reflect.ValueOf(strings.TrimSpace(parsedArgs[i]))
+func (p *ruleParser) parseMatcher() (matcher, error) {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.expression) && unicode.IsLetter(rune(p.expression[p.pos])) {
+		p.pos++
+	}
+	name := p.expression[start:p.pos]
+	if len(name) == 0 {
+		if p.atEnd() {
+			return nil, p.errorf("unexpected end of rule")
 		}
-		method := reflect.ValueOf(parsedFunction)
-		if method.IsValid() {
-			resultRoute = method.Call(inputs)[0].Interface().(*mux.Route)
-			if r.err != nil {
-				return nil, r.err
-			}
-			if resultRoute.GetError() != nil {
-				return nil, resultRoute.GetError()
-			}
+		return nil, p.errorf("expected a matcher name, got '%c'", p.expression[p.pos])
+	}
+	function, ok := p.functions()[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown matcher '%s'", name)
+	}
+	if !p.consume(":") {
+		return nil, p.errorf("expected ':' after '%s'", name)
+	}
+	argsPos := p.pos
+	args := p.parseArgs()
+	if len(args) == 0 {
+		p.pos = argsPos
+		return nil, p.errorf("missing arguments for '%s'", name)
+	}
+	m, err := function(args...)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("%s", err)
+	}
+	if strings.HasSuffix(name, "Strip") {
+		p.strips++
+	}
+	return m, nil
+}
 
-		} else {
-			return nil, errors.New("Method not found: '" + parsedFunctions[0] + "'")
+// parseArgs reads comma separated arguments up to the next operator or
+// closing parenthesis. Operators and commas inside {} (mux variables) or
+// inside parentheses opened in the arguments are part of the arguments.
+func (p *ruleParser) parseArgs() []string {
+	var args []string
+	braces, parens := 0, 0
+	start := p.pos
+	addArg := func(end int) {
+		if arg := strings.TrimSpace(p.expression[start:end]); len(arg) > 0 {
+			args = append(args, arg)
+		}
+	}
+	for ; p.pos < len(p.expression); p.pos++ {
+		c := p.expression[p.pos]
+		if braces > 0 {
+			switch c {
+			case '{':
+				braces++
+			case '}':
+				braces--
+			}
+			continue
+		}
+		switch {
+		case c == '{':
+			braces++
+		case c == '(':
+			parens++
+		case c == ')' && parens > 0:
+			parens--
+		case parens > 0:
+		case c == ')', c == ';', p.lookingAt("&&"), p.lookingAt("||"):
+			addArg(p.pos)
+			return args
+		case c == ',':
+			addArg(p.pos)
+			start = p.pos + 1
 		}
 	}
-	return resultRoute, nil
+	addArg(p.pos)
+	return args
+}
+
+func (p *ruleParser) functions() map[string]func(...string) (matcher, error) {
+	return map[string]func(...string) (matcher, error){
+		"Host":            p.rules.host,
+		"HostRegexp":      p.rules.hostRegexp,
+		"Path":            p.rules.path,
+		"PathStrip":       p.rules.pathStrip,
+		"PathPrefix":      p.rules.pathPrefix,
+		"PathPrefixStrip": p.rules.pathPrefixStrip,
+		"Method":          p.rules.methods,
+		"Headers":         p.rules.headers,
+		"HeadersRegexp":   p.rules.headersRegexp,
+	}
+}
+
+func (p *ruleParser) skipSpaces() {
+	for p.pos < len(p.expression) && unicode.IsSpace(rune(p.expression[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *ruleParser) atEnd() bool {
+	p.skipSpaces()
+	return p.pos >= len(p.expression)
+}
+
+func (p *ruleParser) lookingAt(token string) bool {
+	return strings.HasPrefix(p.expression[p.pos:], token)
+}
+
+func (p *ruleParser) consume(token string) bool {
+	p.skipSpaces()
+	if p.lookingAt(token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Error parsing rule '%s': %s at column %d", p.expression, fmt.Sprintf(format, args...), p.pos+1)
 }
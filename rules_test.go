@@ -4,6 +4,7 @@ import (
 	"github.com/containous/mux"
 	"net/http"
 	"net/url"
+	"reflect"
 	"testing"
 )
 
@@ -53,6 +54,94 @@ func TestParseTwoRules(t *testing.T) {
 	}
 }
 
+func TestParseBooleanRules(t *testing.T) {
+	cases := []struct {
+		expression string
+		url        string
+		method     string
+		expected   bool
+	}{
+		{"Host:foo.bar && Path:/foobar", "http://foo.bar/foobar", "GET", true},
+		{"Host:foo.bar && Path:/foobar", "http://foo.bar/other", "GET", false},
+		{"Host:foo.bar || Host:bar.foo", "http://bar.foo/", "GET", true},
+		{"Host:foo.bar || Host:bar.foo", "http://baz.foo/", "GET", false},
+		{"!Host:foo.bar", "http://foo.bar/", "GET", false},
+		{"!Host:foo.bar", "http://bar.foo/", "GET", true},
+		{"(Host:a.com || Host:b.com) && !PathPrefix:/admin", "http://b.com/public", "GET", true},
+		{"(Host:a.com || Host:b.com) && !PathPrefix:/admin", "http://a.com/admin/users", "GET", false},
+		{"(Host:a.com || Host:b.com) && !PathPrefix:/admin", "http://c.com/public", "GET", false},
+		{"Host:a.com || Host:b.com && Path:/b", "http://a.com/a", "GET", true},
+		{"Host:a.com || Host:b.com && Path:/b", "http://b.com/a", "GET", false},
+		{"!(Method:POST,PUT) && Path:/foo", "http://foo.bar/foo", "GET", true},
+		{"!(Method:POST,PUT) && Path:/foo", "http://foo.bar/foo", "PUT", false},
+		{"Host:foo.bar;PathPrefix:/api,/v1;", "http://foo.bar/v1/users", "GET", true},
+		{"HostRegexp:{subdomain:[a-z]+}.foo.bar && Path:/articles/{id:[0-9]{1,3}}", "http://api.foo.bar/articles/42", "GET", true},
+		{"HostRegexp:{subdomain:[a-z]+}.foo.bar && Path:/articles/{id:[0-9]{1,3}}", "http://api.foo.bar/articles/4242", "GET", false},
+		{"Path:/{id:[0-9]+}(a;b) && Host:foo.bar", "http://foo.bar/42(a;b)", "GET", true},
+		{"Path:/{id:[0-9]+}(a;b) && Host:foo.bar", "http://foo.bar/42", "GET", false},
+		{"PathPrefix:/x(a,b)", "http://foo.bar/x(a,b)/users", "GET", true},
+		{"PathPrefix:/x(a,b)", "http://foo.bar/x(a/users", "GET", false},
+		{"PathPrefix:/x(a||b)", "http://foo.bar/x(a||b)/users", "GET", true},
+	}
+
+	for _, c := range cases {
+		router := mux.NewRouter()
+		rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		routeResult, err := rules.Parse(c.expression)
+		if err != nil {
+			t.Fatalf("Error while building route for %s: %v", c.expression, err)
+		}
+		request, _ := http.NewRequest(c.method, c.url, nil)
+		if routeMatch := routeResult.Match(request, &mux.RouteMatch{}); routeMatch != c.expected {
+			t.Errorf("Rule %s on %s %s: expected match %v, got %v", c.expression, c.method, c.url, c.expected, routeMatch)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"", "Empty rule"},
+		{"Foo:bar", "Error parsing rule 'Foo:bar': unknown matcher 'Foo' at column 1"},
+		{"Host:foo.bar && Bar:/foo", "Error parsing rule 'Host:foo.bar && Bar:/foo': unknown matcher 'Bar' at column 17"},
+		{"Host", "Error parsing rule 'Host': expected ':' after 'Host' at column 5"},
+		{"Host:", "Error parsing rule 'Host:': missing arguments for 'Host' at column 6"},
+		{"(Host:foo.bar", "Error parsing rule '(Host:foo.bar': missing ')' at column 14"},
+		{"Host:foo.bar)", "Error parsing rule 'Host:foo.bar)': unexpected ')' at column 13"},
+		{"Host:foo.bar &&", "Error parsing rule 'Host:foo.bar &&': unexpected end of rule at column 16"},
+		{"Host:foo.bar || || Path:/", "Error parsing rule 'Host:foo.bar || || Path:/': expected a matcher name, got '|' at column 17"},
+		{"!PathPrefixStrip:/foo", "Error parsing rule '!PathPrefixStrip:/foo': PathStrip and PathPrefixStrip can't be negated at column 22"},
+		{"Host:foo.bar || PathStrip:/foo", "Error parsing rule 'Host:foo.bar || PathStrip:/foo': PathStrip and PathPrefixStrip can't be combined with '||' at column 31"},
+		{"(PathPrefixStrip:/foo && Host:foo.bar) || Host:bar.foo", "Error parsing rule '(PathPrefixStrip:/foo && Host:foo.bar) || Host:bar.foo': PathStrip and PathPrefixStrip can't be combined with '||' at column 55"},
+	}
+
+	for _, c := range cases {
+		rules := &Rules{route: &serverRoute{route: mux.NewRouter().NewRoute()}}
+		_, err := rules.Parse(c.expression)
+		if err == nil {
+			t.Fatalf("Expected an error for rule %q", c.expression)
+		}
+		if err.Error() != c.expected {
+			t.Errorf("Rule %q: expected error %q, got %q", c.expression, c.expected, err.Error())
+		}
+	}
+}
+
+func TestParseStripPrefixes(t *testing.T) {
+	serverRoute := &serverRoute{route: mux.NewRouter().NewRoute()}
+	rules := &Rules{route: serverRoute}
+	_, err := rules.Parse("(Host:foo.bar || Host:bar.foo) && PathPrefixStrip:/foo && PathPrefixStrip:/foo/bar,/baz")
+	if err != nil {
+		t.Fatalf("Error while building route: %v", err)
+	}
+	expected := []string{"/foo/bar", "/foo", "/baz"}
+	if !reflect.DeepEqual(serverRoute.stripPrefixes, expected) {
+		t.Fatalf("expected strip prefixes %v, got %v", expected, serverRoute.stripPrefixes)
+	}
+}
+
 func TestPriorites(t *testing.T) {
 	router := mux.NewRouter()
 	router.StrictSlash(true)
@@ -8,7 +8,9 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,27 +18,45 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/negroni"
 	"github.com/containous/mux"
+	"github.com/containous/traefik/healthcheck"
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/provider"
+	"github.com/containous/traefik/proxyproto"
 	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/slowstart"
+	"github.com/containous/traefik/tcp"
 	"github.com/containous/traefik/types"
+	"github.com/containous/traefik/udp"
 	"github.com/mailgun/manners"
 	"github.com/streamrail/concurrent-map"
 	"github.com/vulcand/oxy/cbreaker"
 	"github.com/vulcand/oxy/connlimit"
 	"github.com/vulcand/oxy/forward"
+	"github.com/vulcand/oxy/ratelimit"
 	"github.com/vulcand/oxy/roundrobin"
 	"github.com/vulcand/oxy/utils"
+	"golang.org/x/net/http2"
 )
 
 var oxyLogger = &OxyLogger{}
 
+const stickyCookieName = "_TRAEFIK_BACKEND"
+
+const (
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultRateLimitExtractor  = "client.ip"
+	defaultRateLimitPeriod     = time.Second
+)
+
 // Server is the reverse-proxy/load-balancer engine
 type Server struct {
 	serverEntryPoints          serverEntryPoints
@@ -49,13 +69,22 @@ type Server struct {
 	globalConfiguration        GlobalConfiguration
 	loggerMiddleware           *middlewares.Logger
 	routinesPool               safe.Pool
+	healthCheck                *healthcheck.HealthCheck
+	slowStart                  *slowstart.SlowStart
+	transports                 *backendTransports
+	outlierDetectors           safe.Safe
 }
 
 type serverEntryPoints map[string]*serverEntryPoint
 
 type serverEntryPoint struct {
-	httpServer *manners.GracefulServer
-	httpRouter *middlewares.HandlerSwitcher
+	httpServer   *manners.GracefulServer
+	httpRouter   *middlewares.HandlerSwitcher
+	tcpServer    *tcp.Server
+	tcpRouter    *tcp.Router
+	udpServer    *udp.Server
+	udpFrontend  *udp.Frontend
+	certificates *certificateStore
 }
 
 type serverRoute struct {
@@ -78,13 +107,17 @@ func NewServer(globalConfiguration GlobalConfiguration) *Server {
 	server.currentConfigurations.Set(currentConfigurations)
 	server.globalConfiguration = globalConfiguration
 	server.loggerMiddleware = middlewares.NewLogger(globalConfiguration.AccessLogsFile)
+	server.healthCheck = healthcheck.New()
+	server.slowStart = slowstart.New()
+	server.transports = newBackendTransports(globalConfiguration.MaxIdleConnsPerHost)
+	server.outlierDetectors.Set(map[string]*middlewares.OutlierDetector{})
 
 	return server
 }
 
 // Start starts the server and blocks until server is shutted down.
 func (server *Server) Start() {
-	server.startHTTPServers()
+	server.startServers()
 	server.routinesPool.Go(func(stop chan bool) {
 		server.listenProviders(stop)
 	})
@@ -100,7 +133,13 @@ func (server *Server) Start() {
 // Stop stops the server
 func (server *Server) Stop() {
 	for _, serverEntryPoint := range server.serverEntryPoints {
-		serverEntryPoint.httpServer.BlockingClose()
+		if serverEntryPoint.tcpServer != nil {
+			serverEntryPoint.tcpServer.Close()
+		} else if serverEntryPoint.udpServer != nil {
+			serverEntryPoint.udpServer.Close()
+		} else {
+			serverEntryPoint.httpServer.BlockingClose()
+		}
 	}
 	server.stopChan <- true
 }
@@ -108,6 +147,8 @@ func (server *Server) Stop() {
 // Close destroys the server
 func (server *Server) Close() {
 	server.routinesPool.Stop()
+	server.healthCheck.Stop()
+	server.slowStart.Stop()
 	close(server.configurationChan)
 	close(server.configurationValidatedChan)
 	close(server.signals)
@@ -115,16 +156,34 @@ func (server *Server) Close() {
 	server.loggerMiddleware.Close()
 }
 
-func (server *Server) startHTTPServers() {
+func (server *Server) startServers() {
 	server.serverEntryPoints = server.buildEntryPoints(server.globalConfiguration)
 	for newServerEntryPointName, newServerEntryPoint := range server.serverEntryPoints {
+		if server.globalConfiguration.EntryPoints[newServerEntryPointName].Protocol == "udp" {
+			udpServer, err := server.prepareUDPServer(newServerEntryPointName, server.globalConfiguration.EntryPoints[newServerEntryPointName])
+			if err != nil {
+				log.Fatal("Error preparing server: ", err)
+			}
+			newServerEntryPoint.udpServer = udpServer
+			go server.startUDPServer(udpServer)
+			continue
+		}
+		if newServerEntryPoint.tcpRouter != nil {
+			tcpServer, err := server.prepareTCPServer(newServerEntryPointName, server.globalConfiguration.EntryPoints[newServerEntryPointName])
+			if err != nil {
+				log.Fatal("Error preparing server: ", err)
+			}
+			newServerEntryPoint.tcpServer = tcpServer
+			go server.startTCPServer(tcpServer, server.globalConfiguration.EntryPoints[newServerEntryPointName])
+			continue
+		}
 		newsrv, err := server.prepareServer(newServerEntryPointName, newServerEntryPoint.httpRouter, server.globalConfiguration.EntryPoints[newServerEntryPointName], nil, server.loggerMiddleware, metrics)
 		if err != nil {
 			log.Fatal("Error preparing server: ", err)
 		}
 		serverEntryPoint := server.serverEntryPoints[newServerEntryPointName]
 		serverEntryPoint.httpServer = newsrv
-		go server.startServer(serverEntryPoint.httpServer, server.globalConfiguration)
+		go server.startServer(serverEntryPoint.httpServer, server.globalConfiguration.EntryPoints[newServerEntryPointName], server.globalConfiguration)
 	}
 }
 
@@ -141,9 +200,9 @@ func (server *Server) listenProviders(stop chan bool) {
 			}
 			server.defaultConfigurationValues(configMsg.Configuration)
 			currentConfigurations := server.currentConfigurations.Get().(configs)
-			jsonConf, _ := json.Marshal(configMsg.Configuration)
+			jsonConf, _ := json.Marshal(redactConfiguration(configMsg.Configuration))
 			log.Debugf("Configuration received from provider %s: %s", configMsg.ProviderName, string(jsonConf))
-			if configMsg.Configuration == nil || configMsg.Configuration.Backends == nil && configMsg.Configuration.Frontends == nil {
+			if isEmptyConfiguration(configMsg.Configuration) {
 				log.Infof("Skipping empty Configuration for provider %s", configMsg.ProviderName)
 			} else if reflect.DeepEqual(currentConfigurations[configMsg.ProviderName], configMsg.Configuration) {
 				log.Infof("Skipping same configuration for provider %s", configMsg.ProviderName)
@@ -173,6 +232,14 @@ func (server *Server) listenProviders(stop chan bool) {
 	}
 }
 
+// isEmptyConfiguration returns whether a provider configuration defines
+// neither frontends nor backends.
+func isEmptyConfiguration(configuration *types.Configuration) bool {
+	return configuration == nil || configuration.Backends == nil && configuration.Frontends == nil &&
+		configuration.TCPBackends == nil && configuration.TCPFrontends == nil &&
+		configuration.UDPBackends == nil && configuration.UDPFrontends == nil
+}
+
 func (server *Server) defaultConfigurationValues(configuration *types.Configuration) {
 	if configuration == nil || configuration.Frontends == nil {
 		return
@@ -213,6 +280,19 @@ func (server *Server) listenConfigurations(stop chan bool) {
 			newServerEntryPoints, err := server.loadConfig(newConfigurations, server.globalConfiguration)
 			if err == nil {
 				for newServerEntryPointName, newServerEntryPoint := range newServerEntryPoints {
+					if newServerEntryPoint.certificates != nil {
+						server.serverEntryPoints[newServerEntryPointName].certificates.update(newServerEntryPoint.certificates)
+					}
+					if udpServer := server.serverEntryPoints[newServerEntryPointName].udpServer; udpServer != nil {
+						udpServer.UpdateFrontend(newServerEntryPoint.udpFrontend)
+						log.Infof("Server configuration reloaded on %s", udpServer.Addr)
+						continue
+					}
+					if newServerEntryPoint.tcpRouter != nil {
+						server.serverEntryPoints[newServerEntryPointName].tcpServer.UpdateRouter(newServerEntryPoint.tcpRouter)
+						log.Infof("Server configuration reloaded on %s", server.serverEntryPoints[newServerEntryPointName].tcpServer.Addr)
+						continue
+					}
 					server.serverEntryPoints[newServerEntryPointName].httpRouter.UpdateHandler(newServerEntryPoint.httpRouter.GetHandler())
 					log.Infof("Server configuration reloaded on %s", server.serverEntryPoints[newServerEntryPointName].httpServer.Addr)
 				}
@@ -294,6 +374,9 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 	if err != nil {
 		return nil, err
 	}
+	if err := tlsOption.applyPolicy(config); err != nil {
+		return nil, err
+	}
 
 	if len(tlsOption.ClientCAFiles) > 0 {
 		pool := x509.NewCertPool()
@@ -329,7 +412,29 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 			return nil, errors.New("Unknown entrypoint " + server.globalConfiguration.ACME.EntryPoint + " for ACME configuration")
 		}
 	}
-	if len(config.Certificates) == 0 {
+	if serverEntryPoint, ok := server.serverEntryPoints[entryPointName]; ok && serverEntryPoint.certificates != nil {
+		// the certificates of the providers come first, then the ACME ones
+		certificates := serverEntryPoint.certificates
+		nextGetCertificate := config.GetCertificate
+		hasStaticCertificates := len(config.Certificates) > 0
+		if !hasStaticCertificates && nextGetCertificate == nil {
+			log.Warnf("No static certificates for TLS entrypoint %s, only serving the certificates of the providers", entryPointName)
+		}
+		config.GetCertificate = func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if certificate := certificates.getCertificate(clientHello.ServerName); certificate != nil {
+				return certificate, nil
+			}
+			if nextGetCertificate != nil {
+				if certificate, err := nextGetCertificate(clientHello); certificate != nil || err != nil {
+					return certificate, err
+				}
+			}
+			if !hasStaticCertificates {
+				return nil, fmt.Errorf("No certificate for server name %q on TLS entrypoint %s", clientHello.ServerName, entryPointName)
+			}
+			return nil, nil
+		}
+	} else if len(config.Certificates) == 0 {
 		return nil, errors.New("No certificates found for TLS entrypoint " + entryPointName)
 	}
 	// BuildNameToCertificate parses the CommonName and SubjectAlternateName fields
@@ -338,9 +443,20 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 	return config, nil
 }
 
-func (server *Server) startServer(srv *manners.GracefulServer, globalConfiguration GlobalConfiguration) {
+func (server *Server) startServer(srv *manners.GracefulServer, entryPoint *EntryPoint, globalConfiguration GlobalConfiguration) {
 	log.Infof("Starting server on %s", srv.Addr)
-	if srv.TLSConfig != nil {
+	if entryPoint.ProxyProtocol != nil {
+		listener, err := listenProxyProtocol(srv.Addr, entryPoint.ProxyProtocol)
+		if err != nil {
+			log.Fatal("Error creating server: ", err)
+		}
+		if srv.TLSConfig != nil {
+			listener = tls.NewListener(listener, srv.TLSConfig)
+		}
+		if err := srv.Serve(listener); err != nil {
+			log.Fatal("Error creating server: ", err)
+		}
+	} else if srv.TLSConfig != nil {
 		if err := srv.ListenAndServeTLSWithConfig(srv.TLSConfig); err != nil {
 			log.Fatal("Error creating server: ", err)
 		}
@@ -352,12 +468,31 @@ func (server *Server) startServer(srv *manners.GracefulServer, globalConfigurati
 	log.Info("Server stopped")
 }
 
-func (server *Server) prepareServer(entryPointName string, router *middlewares.HandlerSwitcher, entryPoint *EntryPoint, oldServer *manners.GracefulServer, middlewares ...negroni.Handler) (*manners.GracefulServer, error) {
+func (server *Server) prepareServer(entryPointName string, router *middlewares.HandlerSwitcher, entryPoint *EntryPoint, oldServer *manners.GracefulServer, handlers ...negroni.Handler) (*manners.GracefulServer, error) {
 	log.Infof("Preparing server %s %+v", entryPointName, entryPoint)
 	// middlewares
 	var negroni = negroni.New()
-	for _, middleware := range middlewares {
-		negroni.Use(middleware)
+	for _, handler := range handlers {
+		negroni.Use(handler)
+	}
+	if entryPoint.ForwardedHeaders != nil {
+		forwardedHeaders, err := middlewares.NewForwardedHeaders(entryPoint.ForwardedHeaders.TrustedIPs, entryPoint.ForwardedHeaders.Forwarded)
+		if err != nil {
+			log.Fatalf("Error creating forwarded headers for entrypoint %s: %s", entryPointName, err)
+			return nil, err
+		}
+		negroni.Use(forwardedHeaders)
+	}
+	if len(entryPoint.WhitelistSourceRange) > 0 {
+		ipWhitelister, err := middlewares.NewIPWhitelister(entryPoint.WhitelistSourceRange, entryPoint.WhitelistXForwardedForDepth)
+		if err != nil {
+			log.Fatalf("Error creating IP whitelister for entrypoint %s: %s", entryPointName, err)
+			return nil, err
+		}
+		negroni.Use(ipWhitelister)
+	}
+	if entryPoint.Compress {
+		negroni.Use(middlewares.NewCompress())
 	}
 	negroni.UseHandler(router)
 	tlsConfig, err := server.createTLSConfig(entryPointName, entryPoint.TLS, router)
@@ -366,19 +501,22 @@ func (server *Server) prepareServer(entryPointName string, router *middlewares.H
 		return nil, err
 	}
 
-	if oldServer == nil {
-		return manners.NewWithServer(
-			&http.Server{
-				Addr:      entryPoint.Address,
-				Handler:   negroni,
-				TLSConfig: tlsConfig,
-			}), nil
-	}
-	gracefulServer, err := oldServer.HijackListener(&http.Server{
+	httpServer := &http.Server{
 		Addr:      entryPoint.Address,
 		Handler:   negroni,
 		TLSConfig: tlsConfig,
-	}, tlsConfig)
+	}
+	if tlsConfig != nil {
+		// HTTP/2 is negotiated with ALPN on TLS entrypoints, unless their
+		// cipher suites don't allow it
+		if err := http2.ConfigureServer(httpServer, nil); err != nil {
+			log.Warnf("HTTP/2 disabled on entrypoint %s: %s", entryPointName, err)
+		}
+	}
+	if oldServer == nil {
+		return manners.NewWithServer(httpServer), nil
+	}
+	gracefulServer, err := oldServer.HijackListener(httpServer, tlsConfig)
 	if err != nil {
 		log.Fatalf("Error hijacking server %s", err)
 		return nil, err
@@ -386,12 +524,90 @@ func (server *Server) prepareServer(entryPointName string, router *middlewares.H
 	return gracefulServer, nil
 }
 
+// prepareTCPServer creates the server of a TCP entrypoint, terminating TLS with
+// the certificates of the entrypoint if any.
+func (server *Server) prepareTCPServer(entryPointName string, entryPoint *EntryPoint) (*tcp.Server, error) {
+	log.Infof("Preparing TCP server %s %+v", entryPointName, entryPoint)
+	if server.globalConfiguration.ACME != nil && server.globalConfiguration.ACME.EntryPoint == entryPointName {
+		return nil, errors.New("ACME is not supported on TCP entrypoint " + entryPointName)
+	}
+	tlsConfig, err := server.createTLSConfig(entryPointName, entryPoint.TLS, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tcp.NewServer(entryPoint.Address, tlsConfig), nil
+}
+
+func (server *Server) startTCPServer(tcpServer *tcp.Server, entryPoint *EntryPoint) {
+	log.Infof("Starting TCP server on %s", tcpServer.Addr)
+	if entryPoint.ProxyProtocol != nil {
+		listener, err := listenProxyProtocol(tcpServer.Addr, entryPoint.ProxyProtocol)
+		if err != nil {
+			log.Fatal("Error creating server: ", err)
+		}
+		if err := tcpServer.Serve(listener); err != nil {
+			log.Fatal("Error creating server: ", err)
+		}
+	} else if err := tcpServer.ListenAndServe(); err != nil {
+		log.Fatal("Error creating server: ", err)
+	}
+	log.Info("Server stopped")
+}
+
+// listenProxyProtocol listens on addr, reading the PROXY protocol headers of
+// the connections from the trusted IPs.
+func listenProxyProtocol(addr string, proxyProtocol *ProxyProtocol) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	proxyListener, err := proxyproto.NewListener(listener, proxyProtocol.TrustedIPs)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return proxyListener, nil
+}
+
+// prepareUDPServer creates the server of a UDP entrypoint.
+func (server *Server) prepareUDPServer(entryPointName string, entryPoint *EntryPoint) (*udp.Server, error) {
+	log.Infof("Preparing UDP server %s %+v", entryPointName, entryPoint)
+	if entryPoint.TLS != nil {
+		return nil, errors.New("TLS is not supported on UDP entrypoint " + entryPointName)
+	}
+	if entryPoint.ProxyProtocol != nil {
+		return nil, errors.New("PROXY protocol is not supported on UDP entrypoint " + entryPointName)
+	}
+	return udp.NewServer(entryPoint.Address), nil
+}
+
+func (server *Server) startUDPServer(udpServer *udp.Server) {
+	log.Infof("Starting UDP server on %s", udpServer.Addr)
+	if err := udpServer.ListenAndServe(); err != nil {
+		log.Fatal("Error creating server: ", err)
+	}
+	log.Info("Server stopped")
+}
+
 func (server *Server) buildEntryPoints(globalConfiguration GlobalConfiguration) map[string]*serverEntryPoint {
 	serverEntryPoints := make(map[string]*serverEntryPoint)
-	for entryPointName := range globalConfiguration.EntryPoints {
-		router := server.buildDefaultHTTPRouter()
-		serverEntryPoints[entryPointName] = &serverEntryPoint{
-			httpRouter: middlewares.NewHandlerSwitcher(router),
+	for entryPointName, entryPoint := range globalConfiguration.EntryPoints {
+		if entryPoint.Protocol == "udp" {
+			serverEntryPoints[entryPointName] = &serverEntryPoint{}
+			continue
+		}
+		if entryPoint.Protocol == "tcp" {
+			serverEntryPoints[entryPointName] = &serverEntryPoint{
+				tcpRouter: tcp.NewRouter(),
+			}
+		} else {
+			router := server.buildDefaultHTTPRouter()
+			serverEntryPoints[entryPointName] = &serverEntryPoint{
+				httpRouter: middlewares.NewHandlerSwitcher(router),
+			}
+		}
+		if entryPoint.TLS != nil && providesTLSCertificates(globalConfiguration) {
+			serverEntryPoints[entryPointName].certificates = newCertificateStore()
 		}
 	}
 	return serverEntryPoints
@@ -403,8 +619,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 	serverEntryPoints := server.buildEntryPoints(globalConfiguration)
 	redirectHandlers := make(map[string]http.Handler)
 
-	backends := map[string]http.Handler{}
-	backend2FrontendMap := map[string]string{}
+	loaded := newLoadedBackends()
 	for _, configuration := range configurations {
 		frontendNames := sortedFrontendNamesForConfig(configuration)
 	frontend:
@@ -413,13 +628,6 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 
 			log.Debugf("Creating frontend %s", frontendName)
 
-			fwd, err := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader))
-			if err != nil {
-				log.Errorf("Error creating forwarder for frontend %s: %v", frontendName, err)
-				log.Errorf("Skipping frontend %s...", frontendName)
-				continue frontend
-			}
-			saveBackend := middlewares.NewSaveBackend(fwd)
 			if len(frontend.EntryPoints) == 0 {
 				log.Errorf("No entrypoint defined for frontend %s, defaultEntryPoints:%s", frontendName, globalConfiguration.DefaultEntryPoints)
 				log.Errorf("Skipping frontend %s...", frontendName)
@@ -432,6 +640,11 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 					log.Errorf("Skipping frontend %s...", frontendName)
 					continue frontend
 				}
+				if serverEntryPoints[entryPointName].httpRouter == nil {
+					log.Errorf("Entrypoint '%s' of frontend %s is not an HTTP entrypoint", entryPointName, frontendName)
+					log.Errorf("Skipping frontend %s...", frontendName)
+					continue frontend
+				}
 				newServerRoute := &serverRoute{route: serverEntryPoints[entryPointName].httpRouter.GetHandler().NewRoute().Name(frontendName)}
 				for routeName, route := range frontend.Routes {
 					err := getRoute(newServerRoute, &route)
@@ -455,107 +668,22 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						redirectHandlers[entryPointName] = handler
 					}
 				} else {
-					if backends[frontend.Backend] == nil {
-						log.Debugf("Creating backend %s", frontend.Backend)
-						var lb http.Handler
-						rr, _ := roundrobin.New(saveBackend)
-						if configuration.Backends[frontend.Backend] == nil {
-							log.Errorf("Undefined backend '%s' for frontend %s", frontend.Backend, frontendName)
-							log.Errorf("Skipping frontend %s...", frontendName)
-							continue frontend
-						}
-						lbMethod, err := types.NewLoadBalancerMethod(configuration.Backends[frontend.Backend].LoadBalancer)
-						if err != nil {
-							log.Errorf("Error loading load balancer method '%+v' for frontend %s: %v", configuration.Backends[frontend.Backend].LoadBalancer, frontendName, err)
-							log.Errorf("Skipping frontend %s...", frontendName)
-							continue frontend
-						}
-						switch lbMethod {
-						case types.Drr:
-							log.Debugf("Creating load-balancer drr")
-							rebalancer, _ := roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger))
-							lb = rebalancer
-							for serverName, server := range configuration.Backends[frontend.Backend].Servers {
-								url, err := url.Parse(server.URL)
-								if err != nil {
-									log.Errorf("Error parsing server URL %s: %v", server.URL, err)
-									log.Errorf("Skipping frontend %s...", frontendName)
-									continue frontend
-								}
-								backend2FrontendMap[url.String()] = frontendName
-								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
-								if err := rebalancer.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
-									log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
-									log.Errorf("Skipping frontend %s...", frontendName)
-									continue frontend
-								}
-							}
-						case types.Wrr:
-							log.Debugf("Creating load-balancer wrr")
-							lb = rr
-							for serverName, server := range configuration.Backends[frontend.Backend].Servers {
-								url, err := url.Parse(server.URL)
-								if err != nil {
-									log.Errorf("Error parsing server URL %s: %v", server.URL, err)
-									log.Errorf("Skipping frontend %s...", frontendName)
-									continue frontend
-								}
-								backend2FrontendMap[url.String()] = frontendName
-								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
-								if err := rr.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
-									log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
-									log.Errorf("Skipping frontend %s...", frontendName)
-									continue frontend
-								}
-							}
-						}
-						maxConns := configuration.Backends[frontend.Backend].MaxConn
-						if maxConns != nil && maxConns.Amount != 0 {
-							extractFunc, err := utils.NewExtractor(maxConns.ExtractorFunc)
-							if err != nil {
-								log.Errorf("Error creating connlimit: %v", err)
-								log.Errorf("Skipping frontend %s...", frontendName)
-								continue frontend
-							}
-							log.Debugf("Creating loadd-balancer connlimit")
-							lb, err = connlimit.New(lb, extractFunc, maxConns.Amount, connlimit.Logger(oxyLogger))
-							if err != nil {
-								log.Errorf("Error creating connlimit: %v", err)
-								log.Errorf("Skipping frontend %s...", frontendName)
-								continue frontend
-							}
-						}
-						// retry ?
-						if globalConfiguration.Retry != nil {
-							retries := len(configuration.Backends[frontend.Backend].Servers)
-							if globalConfiguration.Retry.Attempts > 0 {
-								retries = globalConfiguration.Retry.Attempts
-							}
-							lb = middlewares.NewRetry(retries, lb)
-							log.Debugf("Creating retries max attempts %d", retries)
-						}
-
-						var negroni = negroni.New()
-						if configuration.Backends[frontend.Backend].CircuitBreaker != nil {
-							log.Debugf("Creating circuit breaker %s", configuration.Backends[frontend.Backend].CircuitBreaker.Expression)
-							cbreaker, err := middlewares.NewCircuitBreaker(lb, configuration.Backends[frontend.Backend].CircuitBreaker.Expression, cbreaker.Logger(oxyLogger))
-							if err != nil {
-								log.Errorf("Error creating circuit breaker: %v", err)
-								log.Errorf("Skipping frontend %s...", frontendName)
-								continue frontend
-							}
-							negroni.Use(cbreaker)
-						} else {
-							negroni.UseHandler(lb)
-						}
-						backends[frontend.Backend] = negroni
-					} else {
-						log.Debugf("Reusing backend %s", frontend.Backend)
+					backend, err := server.getBackendHandler(loaded, frontendName, frontend, configuration.Backends, globalConfiguration)
+					if err != nil {
+						log.Errorf("Error creating backend for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					frontendHandler, err := server.buildFrontendHandler(frontendName, frontend, backend, configuration.Backends)
+					if err != nil {
+						log.Errorf("Error creating middlewares for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
 					}
 					if frontend.Priority > 0 {
 						newServerRoute.route.Priority(frontend.Priority)
 					}
-					server.wireFrontendBackend(newServerRoute, backends[frontend.Backend])
+					server.wireFrontendBackend(newServerRoute, frontendHandler)
 				}
 				err := newServerRoute.route.GetError()
 				if err != nil {
@@ -564,14 +692,740 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 			}
 		}
 	}
-	middlewares.SetBackend2FrontendMap(&backend2FrontendMap)
+	tcpBackends := make(map[string]*tcp.Backend)
+	udpBackends := make(map[string]*udp.Backend)
+	for _, configuration := range configurations {
+		server.loadTCPConfig(configuration, serverEntryPoints, tcpBackends)
+		server.loadUDPConfig(configuration, serverEntryPoints, udpBackends, globalConfiguration)
+	}
+	server.loadTLSCertificates(configurations, serverEntryPoints)
+	if globalConfiguration.NotFound != nil {
+		notFoundHandler, err := server.buildNotFoundHandler(globalConfiguration.NotFound, configurations)
+		if err != nil {
+			log.Errorf("Error creating not found page: %v", err)
+		} else {
+			for _, serverEntryPoint := range serverEntryPoints {
+				if serverEntryPoint.httpRouter != nil {
+					serverEntryPoint.httpRouter.GetHandler().NotFoundHandler = notFoundHandler
+				}
+			}
+		}
+	}
+	middlewares.SetBackend2FrontendMap(&loaded.backend2Frontend)
+	server.healthCheck.SetBackendsConfiguration(loaded.healthChecks)
+	server.slowStart.SetBackendsConfiguration(loaded.slowStarts)
+	server.transports.commit()
+	previousOutlierDetectors := server.outlierDetectors.Get().(map[string]*middlewares.OutlierDetector)
+	server.outlierDetectors.Set(loaded.outlierDetectors)
+	for _, outlierDetector := range previousOutlierDetectors {
+		outlierDetector.Stop()
+	}
 	//sort routes
 	for _, serverEntryPoint := range serverEntryPoints {
-		serverEntryPoint.httpRouter.GetHandler().SortRoutes()
+		if serverEntryPoint.httpRouter != nil {
+			serverEntryPoint.httpRouter.GetHandler().SortRoutes()
+		}
 	}
 	return serverEntryPoints, nil
 }
 
+// loadedBackends holds the backends created while loading a configuration,
+// so that a backend used by several frontends is only created once.
+type loadedBackends struct {
+	handlers         map[string]http.Handler
+	backend2Frontend map[string]string
+	healthChecks     map[string]*healthcheck.BackendHealthCheck
+	outlierDetectors map[string]*middlewares.OutlierDetector
+	slowStarts       map[string]*slowstart.Backend
+}
+
+func newLoadedBackends() *loadedBackends {
+	return &loadedBackends{
+		handlers:         make(map[string]http.Handler),
+		backend2Frontend: make(map[string]string),
+		healthChecks:     make(map[string]*healthcheck.BackendHealthCheck),
+		outlierDetectors: make(map[string]*middlewares.OutlierDetector),
+		slowStarts:       make(map[string]*slowstart.Backend),
+	}
+}
+
+// getBackendHandler returns the handler forwarding the requests of a frontend,
+// either to its backend or split between several backends.
+func (server *Server) getBackendHandler(loaded *loadedBackends, frontendName string, frontend *types.Frontend, backends map[string]*types.Backend, globalConfiguration GlobalConfiguration) (http.Handler, error) {
+	if frontend.Split == nil {
+		return server.getBackend(loaded, frontend.Backend, frontendName, frontend, backends[frontend.Backend], globalConfiguration)
+	}
+	if len(frontend.Split.Backends) == 0 {
+		return nil, fmt.Errorf("No backend to split traffic between for frontend %s", frontendName)
+	}
+	backendNames := make([]string, 0, len(frontend.Split.Backends))
+	for backendName := range frontend.Split.Backends {
+		backendNames = append(backendNames, backendName)
+	}
+	sort.Strings(backendNames)
+	splitBackends := make([]middlewares.SplitBackend, 0, len(backendNames))
+	for _, backendName := range backendNames {
+		handler, err := server.getBackend(loaded, backendName, frontendName, frontend, backends[backendName], globalConfiguration)
+		if err != nil {
+			return nil, err
+		}
+		splitBackends = append(splitBackends, middlewares.SplitBackend{
+			Name:    backendName,
+			Weight:  frontend.Split.Backends[backendName],
+			Handler: handler,
+		})
+	}
+	log.Debugf("Splitting traffic of frontend %s between backends %v", frontendName, backendNames)
+	return middlewares.NewSplit(frontendName, splitBackends, frontend.Split.Sticky)
+}
+
+// getBackend returns the handler of a backend, creating it with the
+// forwarding settings of the frontend the first time it is used.
+func (server *Server) getBackend(loaded *loadedBackends, backendName string, frontendName string, frontend *types.Frontend, backend *types.Backend, globalConfiguration GlobalConfiguration) (http.Handler, error) {
+	if handler, ok := loaded.handlers[backendName]; ok {
+		log.Debugf("Reusing backend %s", backendName)
+		return handler, nil
+	}
+	if backend == nil {
+		return nil, fmt.Errorf("Undefined backend '%s' for frontend %s", backendName, frontendName)
+	}
+	roundTripper, err := server.transports.get(backendName, backend)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating transport for backend %s: %v", backendName, err)
+	}
+	fwd, err := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader), forward.RoundTripper(middlewares.NewTrailersRoundTripper(roundTripper)))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating forwarder for frontend %s: %v", frontendName, err)
+	}
+	saveBackend := middlewares.NewSaveBackend(middlewares.NewTrailers(fwd))
+	log.Debugf("Creating backend %s", backendName)
+	var lb http.Handler
+	var healthCheckLB healthcheck.LoadBalancer
+	var next http.Handler = saveBackend
+	var outlierDetector *middlewares.OutlierDetector
+	if backend.OutlierDetection != nil {
+		outlierDetector, err = newOutlierDetector(backend, saveBackend)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating outlier detection for backend %s: %v", backendName, err)
+		}
+		if previous, ok := server.outlierDetectors.Get().(map[string]*middlewares.OutlierDetector)[backendName]; ok {
+			outlierDetector.Restore(previous)
+		}
+		next = outlierDetector
+	}
+	var sticky *roundrobin.StickySession
+	var rr *roundrobin.RoundRobin
+	if loadBalancer := backend.LoadBalancer; loadBalancer != nil && loadBalancer.Sticky {
+		log.Debugf("Sticky session with cookie %s", stickyCookieName)
+		sticky = roundrobin.NewStickySession(stickyCookieName)
+		rr, _ = roundrobin.New(next, roundrobin.EnableStickySession(sticky))
+	} else {
+		rr, _ = roundrobin.New(next)
+	}
+	lbMethod, err := types.NewLoadBalancerMethod(backend.LoadBalancer)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading load balancer method '%+v' for frontend %s: %v", backend.LoadBalancer, frontendName, err)
+	}
+	var slowStart *slowstart.Backend
+	if loadBalancer := backend.LoadBalancer; loadBalancer != nil && len(loadBalancer.SlowStart) > 0 {
+		duration, err := time.ParseDuration(loadBalancer.SlowStart)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing slow start duration for backend %s: %v", backendName, err)
+		}
+		if lbMethod == types.Drr || lbMethod == types.Wrr {
+			slowStart = server.slowStart.NewBackend(backendName, duration)
+		} else {
+			log.Warnf("Slow start is not supported by load-balancer %s of backend %s", loadBalancer.Method, backendName)
+		}
+	}
+	switch lbMethod {
+	case types.Drr:
+		log.Debugf("Creating load-balancer drr")
+		rebalancer, _ := roundrobin.NewRebalancer(rr, roundrobin.RebalancerLogger(oxyLogger), roundrobin.RebalancerStickySession(sticky))
+		lb = rebalancer
+		healthCheckLB = rebalancer
+		for serverName, server := range backend.Servers {
+			url, err := parseServerURL(server.URL)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing server URL %s: %v", server.URL, err)
+			}
+			loaded.backend2Frontend[url.String()] = frontendName
+			weight := serverWeight(backend, server)
+			if slowStart != nil {
+				weight = slowStart.AddServer(url, server.Weight)
+			}
+			log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), weight)
+			if err := rebalancer.UpsertServer(url, roundrobin.Weight(weight)); err != nil {
+				return nil, fmt.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+			}
+		}
+	case types.Wrr:
+		log.Debugf("Creating load-balancer wrr")
+		lb = rr
+		healthCheckLB = rr
+		for serverName, server := range backend.Servers {
+			url, err := parseServerURL(server.URL)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing server URL %s: %v", server.URL, err)
+			}
+			loaded.backend2Frontend[url.String()] = frontendName
+			weight := serverWeight(backend, server)
+			if slowStart != nil {
+				weight = slowStart.AddServer(url, server.Weight)
+			}
+			log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), weight)
+			if err := rr.UpsertServer(url, roundrobin.Weight(weight)); err != nil {
+				return nil, fmt.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+			}
+		}
+	case types.LeastConn, types.ConsistentHash:
+		var balancer interface {
+			http.Handler
+			healthcheck.LoadBalancer
+		}
+		if lbMethod == types.LeastConn {
+			log.Debugf("Creating load-balancer leastconn")
+			balancer = middlewares.NewLeastConn(next)
+		} else {
+			hashKey := backend.LoadBalancer.HashKey
+			log.Debugf("Creating load-balancer consistenthash on %s", hashKey)
+			balancer, err = middlewares.NewConsistentHash(next, hashKey)
+			if err != nil {
+				return nil, fmt.Errorf("Error creating load-balancer consistenthash: %v", err)
+			}
+		}
+		lb = balancer
+		healthCheckLB = balancer
+		for serverName, server := range backend.Servers {
+			url, err := parseServerURL(server.URL)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing server URL %s: %v", server.URL, err)
+			}
+			loaded.backend2Frontend[url.String()] = frontendName
+			log.Debugf("Creating server %s at %s", serverName, url.String())
+			if err := balancer.UpsertServer(url); err != nil {
+				return nil, fmt.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
+			}
+		}
+	}
+	if slowStart != nil {
+		log.Debugf("Creating slow start over %s", slowStart.Duration)
+		slowStart.LB = healthCheckLB
+		loaded.slowStarts[backendName] = slowStart
+	}
+	if outlierDetector != nil {
+		log.Debugf("Creating outlier detection for backend %s", backendName)
+		outlierDetector.SetLoadBalancer(healthCheckLB)
+		loaded.outlierDetectors[backendName] = outlierDetector
+	}
+	if backend.HealthCheck != nil {
+		options, err := newHealthCheckOptions(backend, healthCheckLB, roundTripper)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating health check for backend %s: %v", backendName, err)
+		}
+		log.Debugf("Creating health check %s every %s", options.Path, options.Interval)
+		backendHealthCheck := healthcheck.NewBackendHealthCheck(*options)
+		if outlierDetector != nil {
+			outlierDetector.SetHealthCheck(backendHealthCheck)
+		}
+		loaded.healthChecks[backendName] = backendHealthCheck
+	}
+	maxConns := backend.MaxConn
+	if maxConns != nil && maxConns.Amount != 0 {
+		extractFunc, err := utils.NewExtractor(maxConns.ExtractorFunc)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating connlimit: %v", err)
+		}
+		log.Debugf("Creating loadd-balancer connlimit")
+		lb, err = connlimit.New(lb, extractFunc, maxConns.Amount, connlimit.Logger(oxyLogger))
+		if err != nil {
+			return nil, fmt.Errorf("Error creating connlimit: %v", err)
+		}
+	}
+	// retry ?
+	if globalConfiguration.Retry != nil || backend.Retry != nil {
+		retries := len(backend.Servers)
+		var maxRequestBodyBytes int64
+		if globalConfiguration.Retry != nil {
+			if globalConfiguration.Retry.Attempts > 0 {
+				retries = globalConfiguration.Retry.Attempts
+			}
+			maxRequestBodyBytes = globalConfiguration.Retry.MaxRequestBodyBytes
+		}
+		if backendRetry := backend.Retry; backendRetry != nil && backendRetry.Attempts > 0 {
+			retries = backendRetry.Attempts
+		}
+		lb = middlewares.NewRetry(retries, maxRequestBodyBytes, lb)
+		log.Debugf("Creating retries max attempts %d", retries)
+	}
+
+	var negroni = negroni.New()
+	if backend.CircuitBreaker != nil {
+		log.Debugf("Creating circuit breaker %s", backend.CircuitBreaker.Expression)
+		cbreaker, err := middlewares.NewCircuitBreaker(lb, backend.CircuitBreaker.Expression, cbreaker.Logger(oxyLogger))
+		if err != nil {
+			return nil, fmt.Errorf("Error creating circuit breaker: %v", err)
+		}
+		negroni.Use(cbreaker)
+	} else {
+		negroni.UseHandler(lb)
+	}
+	loaded.handlers[backendName] = negroni
+	return negroni, nil
+}
+
+// loadTCPConfig adds the TCP frontends of a configuration to the routers of
+// their entrypoints.
+func (server *Server) loadTCPConfig(configuration *types.Configuration, serverEntryPoints map[string]*serverEntryPoint, backends map[string]*tcp.Backend) {
+	frontendNames := make([]string, 0, len(configuration.TCPFrontends))
+	for frontendName := range configuration.TCPFrontends {
+		frontendNames = append(frontendNames, frontendName)
+	}
+	sort.Strings(frontendNames)
+frontend:
+	for _, frontendName := range frontendNames {
+		frontend := configuration.TCPFrontends[frontendName]
+		log.Debugf("Creating TCP frontend %s", frontendName)
+		if len(frontend.EntryPoints) == 0 {
+			log.Errorf("No entrypoint defined for TCP frontend %s", frontendName)
+			log.Errorf("Skipping frontend %s...", frontendName)
+			continue frontend
+		}
+		for _, entryPointName := range frontend.EntryPoints {
+			if serverEntryPoint, ok := serverEntryPoints[entryPointName]; !ok || serverEntryPoint.tcpRouter == nil {
+				log.Errorf("Undefined TCP entrypoint '%s' for frontend %s", entryPointName, frontendName)
+				log.Errorf("Skipping frontend %s...", frontendName)
+				continue frontend
+			}
+		}
+		backend, err := getTCPBackend(frontend.Backend, configuration.TCPBackends[frontend.Backend], backends)
+		if err != nil {
+			log.Errorf("Error creating backend for TCP frontend %s: %v", frontendName, err)
+			log.Errorf("Skipping frontend %s...", frontendName)
+			continue frontend
+		}
+		for _, entryPointName := range frontend.EntryPoints {
+			log.Debugf("Wiring TCP frontend %s to entryPoint %s", frontendName, entryPointName)
+			err := serverEntryPoints[entryPointName].tcpRouter.AddFrontend(&tcp.Frontend{
+				Name:        frontendName,
+				SNI:         frontend.SNI,
+				Passthrough: frontend.Passthrough,
+				Backend:     backend,
+			})
+			if err != nil {
+				log.Errorf("Error wiring TCP frontend %s to entryPoint %s: %v", frontendName, entryPointName, err)
+			}
+		}
+	}
+}
+
+// getTCPBackend returns the load-balancer of a TCP backend, creating it the
+// first time it is used.
+func getTCPBackend(backendName string, configuration *types.TCPBackend, backends map[string]*tcp.Backend) (*tcp.Backend, error) {
+	if backend, ok := backends[backendName]; ok {
+		return backend, nil
+	}
+	if configuration == nil {
+		return nil, fmt.Errorf("Undefined TCP backend '%s'", backendName)
+	}
+	dialTimeout := defaultDialTimeout
+	if len(configuration.DialTimeout) > 0 {
+		var err error
+		if dialTimeout, err = time.ParseDuration(configuration.DialTimeout); err != nil {
+			return nil, err
+		}
+	}
+	log.Debugf("Creating TCP backend %s", backendName)
+	backend := tcp.NewBackend(backendName, dialTimeout)
+	if configuration.ProxyProtocolVersion != 0 {
+		if err := proxyproto.CheckVersion(configuration.ProxyProtocolVersion); err != nil {
+			return nil, err
+		}
+		backend.ProxyProtocolVersion = configuration.ProxyProtocolVersion
+	}
+	serverNames := make([]string, 0, len(configuration.Servers))
+	for serverName := range configuration.Servers {
+		serverNames = append(serverNames, serverName)
+	}
+	sort.Strings(serverNames)
+	for _, serverName := range serverNames {
+		server := configuration.Servers[serverName]
+		log.Debugf("Creating TCP server %s at %s with weight %d", serverName, server.Address, server.Weight)
+		backend.AddServer(server.Address, server.Weight)
+	}
+	backends[backendName] = backend
+	return backend, nil
+}
+
+// loadUDPConfig sets the UDP frontends of a configuration as the frontends of
+// their entrypoints.
+func (server *Server) loadUDPConfig(configuration *types.Configuration, serverEntryPoints map[string]*serverEntryPoint, backends map[string]*udp.Backend, globalConfiguration GlobalConfiguration) {
+	frontendNames := make([]string, 0, len(configuration.UDPFrontends))
+	for frontendName := range configuration.UDPFrontends {
+		frontendNames = append(frontendNames, frontendName)
+	}
+	sort.Strings(frontendNames)
+frontend:
+	for _, frontendName := range frontendNames {
+		frontend := configuration.UDPFrontends[frontendName]
+		log.Debugf("Creating UDP frontend %s", frontendName)
+		if len(frontend.EntryPoints) == 0 {
+			log.Errorf("No entrypoint defined for UDP frontend %s", frontendName)
+			log.Errorf("Skipping frontend %s...", frontendName)
+			continue frontend
+		}
+		for _, entryPointName := range frontend.EntryPoints {
+			if entryPoint, ok := globalConfiguration.EntryPoints[entryPointName]; !ok || entryPoint.Protocol != "udp" {
+				log.Errorf("Undefined UDP entrypoint '%s' for frontend %s", entryPointName, frontendName)
+				log.Errorf("Skipping frontend %s...", frontendName)
+				continue frontend
+			}
+			if existing := serverEntryPoints[entryPointName].udpFrontend; existing != nil {
+				log.Errorf("UDP entrypoint '%s' of frontend %s is already used by frontend %s", entryPointName, frontendName, existing.Name)
+				log.Errorf("Skipping frontend %s...", frontendName)
+				continue frontend
+			}
+		}
+		idleTimeout := udp.DefaultIdleTimeout
+		if len(frontend.IdleTimeout) > 0 {
+			var err error
+			if idleTimeout, err = time.ParseDuration(frontend.IdleTimeout); err != nil {
+				log.Errorf("Error parsing idle timeout of UDP frontend %s: %v", frontendName, err)
+				log.Errorf("Skipping frontend %s...", frontendName)
+				continue frontend
+			}
+		}
+		backend, err := getUDPBackend(frontend.Backend, configuration.UDPBackends[frontend.Backend], backends)
+		if err != nil {
+			log.Errorf("Error creating backend for UDP frontend %s: %v", frontendName, err)
+			log.Errorf("Skipping frontend %s...", frontendName)
+			continue frontend
+		}
+		for _, entryPointName := range frontend.EntryPoints {
+			log.Debugf("Wiring UDP frontend %s to entryPoint %s", frontendName, entryPointName)
+			serverEntryPoints[entryPointName].udpFrontend = &udp.Frontend{
+				Name:        frontendName,
+				Backend:     backend,
+				IdleTimeout: idleTimeout,
+			}
+		}
+	}
+}
+
+// loadTLSCertificates adds the certificates of the configurations to the
+// certificate stores of their TLS entrypoints, the providers being taken in
+// the order of their names.
+func (server *Server) loadTLSCertificates(configurations configs, serverEntryPoints map[string]*serverEntryPoint) {
+	providerNames := make([]string, 0, len(configurations))
+	for providerName := range configurations {
+		providerNames = append(providerNames, providerName)
+	}
+	sort.Strings(providerNames)
+	for _, providerName := range providerNames {
+	certificate:
+		for i, tlsCertificate := range configurations[providerName].TLSCertificates {
+			certificate, err := loadTLSCertificate(tlsCertificate)
+			if err != nil {
+				log.Errorf("Error loading TLS certificate %d of provider %s: %v", i, providerName, err)
+				log.Errorf("Skipping certificate %d of provider %s...", i, providerName)
+				continue certificate
+			}
+			names := strings.Join(certificateNames(certificate.Leaf), ",")
+			log.Debugf("Loading TLS certificate %s from provider %s", names, providerName)
+			entryPointNames := tlsCertificate.EntryPoints
+			if len(entryPointNames) == 0 {
+				for entryPointName, serverEntryPoint := range serverEntryPoints {
+					if serverEntryPoint.certificates != nil {
+						entryPointNames = append(entryPointNames, entryPointName)
+					}
+				}
+			}
+			for _, entryPointName := range entryPointNames {
+				if serverEntryPoint, ok := serverEntryPoints[entryPointName]; !ok || serverEntryPoint.certificates == nil {
+					log.Errorf("Undefined TLS entrypoint '%s' for certificate %s", entryPointName, names)
+					log.Errorf("Skipping certificate %s...", names)
+					continue certificate
+				}
+			}
+			for _, entryPointName := range entryPointNames {
+				serverEntryPoints[entryPointName].certificates.add(certificate)
+			}
+		}
+	}
+}
+
+// getUDPBackend returns the load-balancer of a UDP backend, creating it the
+// first time it is used.
+func getUDPBackend(backendName string, configuration *types.UDPBackend, backends map[string]*udp.Backend) (*udp.Backend, error) {
+	if backend, ok := backends[backendName]; ok {
+		return backend, nil
+	}
+	if configuration == nil {
+		return nil, fmt.Errorf("Undefined UDP backend '%s'", backendName)
+	}
+	log.Debugf("Creating UDP backend %s", backendName)
+	backend := udp.NewBackend(backendName)
+	serverNames := make([]string, 0, len(configuration.Servers))
+	for serverName := range configuration.Servers {
+		serverNames = append(serverNames, serverName)
+	}
+	sort.Strings(serverNames)
+	for _, serverName := range serverNames {
+		server := configuration.Servers[serverName]
+		log.Debugf("Creating UDP server %s at %s with weight %d", serverName, server.Address, server.Weight)
+		backend.AddServer(server.Address, server.Weight)
+	}
+	backends[backendName] = backend
+	return backend, nil
+}
+
+func newHealthCheckOptions(backend *types.Backend, lb healthcheck.LoadBalancer, roundTripper http.RoundTripper) (*healthcheck.Options, error) {
+	if len(backend.HealthCheck.Path) == 0 {
+		return nil, errors.New("Health check path is required")
+	}
+	options := &healthcheck.Options{
+		Path:           backend.HealthCheck.Path,
+		Interval:       defaultHealthCheckInterval,
+		Timeout:        defaultHealthCheckTimeout,
+		ExpectedStatus: backend.HealthCheck.ExpectedStatus,
+		Weights:        make(map[string]int),
+		LB:             lb,
+		Transport:      roundTripper,
+	}
+	if len(backend.HealthCheck.Interval) > 0 {
+		interval, err := time.ParseDuration(backend.HealthCheck.Interval)
+		if err != nil {
+			return nil, err
+		}
+		if interval <= 0 {
+			return nil, errors.New("Health check interval must be positive: " + backend.HealthCheck.Interval)
+		}
+		options.Interval = interval
+	}
+	if len(backend.HealthCheck.Timeout) > 0 {
+		timeout, err := time.ParseDuration(backend.HealthCheck.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		options.Timeout = timeout
+	}
+	for _, server := range backend.Servers {
+		url, err := parseServerURL(server.URL)
+		if err != nil {
+			return nil, err
+		}
+		options.Weights[url.String()] = serverWeight(backend, server)
+	}
+	return options, nil
+}
+
+// parseServerURL parses the URL of a server, the h2c scheme being forwarded
+// as http by the h2c transport of its backend.
+func parseServerURL(rawURL string) (*url.URL, error) {
+	serverURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if serverURL.Scheme == "h2c" {
+		serverURL.Scheme = "http"
+	}
+	return serverURL, nil
+}
+
+// serverWeight returns the weight of a server in its load-balancer, scaled
+// when the backend has a slow start.
+func serverWeight(backend *types.Backend, server types.Server) int {
+	if backend.LoadBalancer != nil && len(backend.LoadBalancer.SlowStart) > 0 {
+		return server.Weight * slowstart.WeightScale
+	}
+	return server.Weight
+}
+
+func newOutlierDetector(backend *types.Backend, next http.Handler) (*middlewares.OutlierDetector, error) {
+	var baseEjectionTime, maxEjectionTime time.Duration
+	if len(backend.OutlierDetection.BaseEjectionTime) > 0 {
+		var err error
+		baseEjectionTime, err = time.ParseDuration(backend.OutlierDetection.BaseEjectionTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(backend.OutlierDetection.MaxEjectionTime) > 0 {
+		var err error
+		maxEjectionTime, err = time.ParseDuration(backend.OutlierDetection.MaxEjectionTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	weights := make(map[string]int)
+	for _, server := range backend.Servers {
+		url, err := parseServerURL(server.URL)
+		if err != nil {
+			return nil, err
+		}
+		weights[url.String()] = serverWeight(backend, server)
+	}
+	return middlewares.NewOutlierDetector(next, backend.OutlierDetection.ConsecutiveErrors, baseEjectionTime, maxEjectionTime, weights), nil
+}
+
+// buildFrontendHandler wraps the backend handler with the middlewares
+// configured on the frontend.
+func (server *Server) buildFrontendHandler(frontendName string, frontend *types.Frontend, backend http.Handler, configurationBackends map[string]*types.Backend) (http.Handler, error) {
+	var negroni = negroni.New()
+	errorPageNames := []string{}
+	for errorPageName := range frontend.Errors {
+		errorPageNames = append(errorPageNames, errorPageName)
+	}
+	sort.Strings(errorPageNames)
+	for _, errorPageName := range errorPageNames {
+		errorPage := frontend.Errors[errorPageName]
+		log.Debugf("Creating error page %s for frontend %s: %v -> %s%s", errorPageName, frontendName, errorPage.Status, errorPage.Backend, errorPage.Query)
+		errorPages, err := server.buildErrorPages(errorPage, errorPage.Status, configurationBackends)
+		if err != nil {
+			return nil, err
+		}
+		negroni.Use(errorPages)
+	}
+	if len(frontend.WhitelistSourceRange) > 0 {
+		log.Debugf("Creating IP whitelister for frontend %s: %v", frontendName, frontend.WhitelistSourceRange)
+		ipWhitelister, err := middlewares.NewIPWhitelister(frontend.WhitelistSourceRange, frontend.WhitelistXForwardedForDepth)
+		if err != nil {
+			return nil, err
+		}
+		negroni.Use(ipWhitelister)
+	}
+	if frontend.Compress {
+		log.Debugf("Creating compress middleware for frontend %s", frontendName)
+		negroni.Use(middlewares.NewCompress())
+	}
+	if frontend.CORS != nil {
+		log.Debugf("Creating CORS middleware for frontend %s", frontendName)
+		negroni.Use(middlewares.NewCORS(frontend.CORS))
+	}
+	if frontend.Headers != nil {
+		log.Debugf("Creating headers middleware for frontend %s", frontendName)
+		negroni.Use(middlewares.NewHeaders(frontend.Headers))
+	}
+	if frontend.BasicAuth != nil {
+		log.Debugf("Creating basic auth for frontend %s", frontendName)
+		basicAuth, err := middlewares.NewBasicAuth(frontend.BasicAuth.Users, frontend.BasicAuth.UsersFile, frontend.BasicAuth.Realm)
+		if err != nil {
+			return nil, err
+		}
+		negroni.Use(basicAuth)
+	}
+	if frontend.DigestAuth != nil {
+		log.Debugf("Creating digest auth for frontend %s", frontendName)
+		digestAuth, err := middlewares.NewDigestAuth(frontend.DigestAuth.Users, frontend.DigestAuth.UsersFile, frontend.DigestAuth.Realm)
+		if err != nil {
+			return nil, err
+		}
+		negroni.Use(digestAuth)
+	}
+	if frontend.ForwardAuth != nil {
+		log.Debugf("Creating forward auth to %s for frontend %s", frontend.ForwardAuth.Address, frontendName)
+		forwardAuth, err := middlewares.NewForwardAuth(frontend.ForwardAuth.Address, frontend.ForwardAuth.AuthResponseHeaders)
+		if err != nil {
+			return nil, err
+		}
+		negroni.Use(forwardAuth)
+	}
+	if frontend.Mirror != nil {
+		percent := 100
+		if frontend.Mirror.Percent != nil {
+			percent = *frontend.Mirror.Percent
+		}
+		log.Debugf("Creating mirror of %d%% of the requests of frontend %s to backend %s", percent, frontendName, frontend.Mirror.Backend)
+		mirrorBackend := configurationBackends[frontend.Mirror.Backend]
+		if mirrorBackend == nil {
+			return nil, fmt.Errorf("Undefined mirror backend '%s'", frontend.Mirror.Backend)
+		}
+		mirrorHandler, err := server.buildSimpleLoadBalancer(frontend.Mirror.Backend, mirrorBackend)
+		if err != nil {
+			return nil, err
+		}
+		negroni.Use(middlewares.NewMirror(mirrorHandler, percent, frontend.Mirror.MaxBodySize))
+	}
+	negroni.UseHandler(backend)
+	if frontend.RateLimit != nil && len(frontend.RateLimit.RateSet) > 0 {
+		log.Debugf("Creating rate limiter for frontend %s", frontendName)
+		return buildRateLimiter(negroni, frontend.RateLimit)
+	}
+	return negroni, nil
+}
+
+// buildErrorPages creates an error pages middleware for the given statuses,
+// served by a load balancer over the servers of the error page backend.
+func (server *Server) buildErrorPages(errorPage *types.ErrorPage, status []string, configurationBackends map[string]*types.Backend) (*middlewares.ErrorPages, error) {
+	backend := configurationBackends[errorPage.Backend]
+	if backend == nil {
+		return nil, fmt.Errorf("Undefined error page backend '%s'", errorPage.Backend)
+	}
+	rr, err := server.buildSimpleLoadBalancer(errorPage.Backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return middlewares.NewErrorPages(status, errorPage.Query, rr)
+}
+
+// buildSimpleLoadBalancer creates a round robin load balancer over the
+// servers of a backend, without its other settings, for the requests sent by
+// traefik itself to this backend.
+func (server *Server) buildSimpleLoadBalancer(backendName string, backend *types.Backend) (http.Handler, error) {
+	roundTripper, err := server.transports.get(backendName, backend)
+	if err != nil {
+		return nil, err
+	}
+	fwd, err := forward.New(forward.Logger(oxyLogger), forward.RoundTripper(roundTripper))
+	if err != nil {
+		return nil, err
+	}
+	rr, err := roundrobin.New(fwd)
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range backend.Servers {
+		url, err := parseServerURL(server.URL)
+		if err != nil {
+			return nil, err
+		}
+		if err := rr.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
+			return nil, err
+		}
+	}
+	return rr, nil
+}
+
+// buildRateLimiter wraps handler with a rate limiter, so that rate limited
+// requests are rejected before any other middleware of the frontend.
+func buildRateLimiter(handler http.Handler, rateLimit *types.RateLimit) (http.Handler, error) {
+	extractorFunc := rateLimit.ExtractorFunc
+	if len(extractorFunc) == 0 {
+		extractorFunc = defaultRateLimitExtractor
+	}
+	extractFunc, err := utils.NewExtractor(extractorFunc)
+	if err != nil {
+		return nil, err
+	}
+	rateSet := ratelimit.NewRateSet()
+	for name, rate := range rateLimit.RateSet {
+		period := defaultRateLimitPeriod
+		if len(rate.Period) > 0 {
+			if period, err = time.ParseDuration(rate.Period); err != nil {
+				return nil, fmt.Errorf("Invalid period for rate %s: %v", name, err)
+			}
+		}
+		burst := rate.Burst
+		if burst == 0 {
+			burst = rate.Average
+		}
+		if err := rateSet.Add(period, rate.Average, burst); err != nil {
+			return nil, fmt.Errorf("Invalid rate %s: %v", name, err)
+		}
+	}
+	return ratelimit.New(handler, extractFunc, rateSet, ratelimit.ErrorHandler(middlewares.NewRateLimitErrorHandler()), ratelimit.Logger(oxyLogger))
+}
+
 func (server *Server) wireFrontendBackend(serverRoute *serverRoute, handler http.Handler) {
 	// strip prefix
 	if len(serverRoute.stripPrefixes) > 0 {
@@ -620,6 +1474,24 @@ func (server *Server) buildDefaultHTTPRouter() *mux.Router {
 	return router
 }
 
+// buildNotFoundHandler creates the handler of the requests matching no
+// frontend, serving the configured page with a 404 status.
+func (server *Server) buildNotFoundHandler(notFound *types.ErrorPage, configurations configs) (http.Handler, error) {
+	for _, configuration := range configurations {
+		if configuration.Backends[notFound.Backend] == nil {
+			continue
+		}
+		errorPages, err := server.buildErrorPages(notFound, []string{strconv.Itoa(http.StatusNotFound)}, configuration.Backends)
+		if err != nil {
+			return nil, err
+		}
+		negroni := negroni.New(errorPages)
+		negroni.UseHandler(http.HandlerFunc(notFoundHandler))
+		return negroni, nil
+	}
+	return nil, fmt.Errorf("Undefined not found page backend '%s'", notFound.Backend)
+}
+
 func getRoute(serverRoute *serverRoute, route *types.Route) error {
 	rules := Rules{route: serverRoute}
 	newRoute, err := rules.Parse(route.Rule)
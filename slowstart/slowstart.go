@@ -0,0 +1,190 @@
+// Package slowstart ramps up the weight of the servers newly added to a
+// load-balancer, so that they are not overwhelmed while warming up.
+package slowstart
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/safe"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+// WeightScale is the factor applied to the configured weights of the servers
+// of a backend with slow start, so that a server with the default weight can
+// also receive a fraction of its traffic while warming up.
+const WeightScale = 10
+
+// rampSteps is the number of weight updates during a ramp.
+const rampSteps = 10
+
+// Backend holds the slow start state of a backend. LB must be set before
+// starting the ramp.
+type Backend struct {
+	Duration time.Duration
+	LB       healthcheck.LoadBalancer
+	existed  bool
+	previous map[string]time.Time
+	servers  map[string]*server
+}
+
+type server struct {
+	url      *url.URL
+	weight   int
+	addedAt  time.Time
+	rampedUp bool
+}
+
+// SlowStart ramps the servers of every backend, and remembers when they were
+// added so that a configuration reload does not restart their ramp.
+type SlowStart struct {
+	stop    chan bool
+	lock    sync.Mutex
+	addedAt map[string]map[string]time.Time
+}
+
+// New returns a new SlowStart.
+func New() *SlowStart {
+	return &SlowStart{addedAt: make(map[string]map[string]time.Time)}
+}
+
+// NewBackend returns the slow start state of a backend, ramping up over
+// duration the servers which were not in this backend in the running
+// configuration. Servers of a backend which was not running are not ramped.
+func (ss *SlowStart) NewBackend(backendName string, duration time.Duration) *Backend {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	previous, existed := ss.addedAt[backendName]
+	return &Backend{
+		Duration: duration,
+		existed:  existed,
+		previous: previous,
+		servers:  make(map[string]*server),
+	}
+}
+
+// AddServer registers a server with its configured weight, and returns the
+// weight it must be added to the load-balancer with.
+func (backend *Backend) AddServer(serverURL *url.URL, weight int) int {
+	var addedAt time.Time
+	if backend.existed {
+		var known bool
+		if addedAt, known = backend.previous[serverURL.String()]; !known {
+			addedAt = time.Now()
+		}
+	}
+	now := time.Now()
+	s := &server{
+		url:      serverURL,
+		weight:   weight,
+		addedAt:  addedAt,
+		rampedUp: addedAt.IsZero() || now.Sub(addedAt) >= backend.Duration,
+	}
+	backend.servers[serverURL.String()] = s
+	return backend.weight(s, now)
+}
+
+// weight returns the weight of a server at the given time: the scaled
+// configured weight, proportionally reduced while the server is warming up.
+func (backend *Backend) weight(s *server, now time.Time) int {
+	weight := s.weight * WeightScale
+	elapsed := now.Sub(s.addedAt)
+	if s.addedAt.IsZero() || elapsed >= backend.Duration || weight <= 0 {
+		return weight
+	}
+	rampedWeight := int(int64(weight) * int64(elapsed) / int64(backend.Duration))
+	if rampedWeight < 1 {
+		return 1
+	}
+	return rampedWeight
+}
+
+// rampUp updates the weight of the warming up servers still in the
+// load-balancer, and returns whether some of them are still warming up.
+func (backend *Backend) rampUp(backendName string, now time.Time) bool {
+	enabledURLs := make(map[string]bool)
+	for _, enabledURL := range backend.LB.Servers() {
+		enabledURLs[enabledURL.String()] = true
+	}
+	warmingUp := false
+	for key, s := range backend.servers {
+		if s.rampedUp {
+			continue
+		}
+		if now.Sub(s.addedAt) >= backend.Duration {
+			s.rampedUp = true
+		} else {
+			warmingUp = true
+		}
+		if !enabledURLs[key] {
+			continue
+		}
+		weight := backend.weight(s, now)
+		log.Debugf("Slow start: setting weight %d to server %s in backend %s", weight, s.url, backendName)
+		if err := backend.LB.UpsertServer(s.url, roundrobin.Weight(weight)); err != nil {
+			log.Errorf("Error setting weight of server %s in backend %s: %v", s.url, backendName, err)
+		}
+	}
+	return warmingUp
+}
+
+// SetBackendsConfiguration stops the running ramps, remembers the servers of
+// the given backends, and starts ramping their warming up servers.
+func (ss *SlowStart) SetBackendsConfiguration(backends map[string]*Backend) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if ss.stop != nil {
+		close(ss.stop)
+	}
+	ss.stop = make(chan bool)
+	ss.addedAt = make(map[string]map[string]time.Time)
+	for backendName, backend := range backends {
+		addedAt := make(map[string]time.Time)
+		for key, s := range backend.servers {
+			addedAt[key] = s.addedAt
+		}
+		ss.addedAt[backendName] = addedAt
+
+		currentBackendName := backendName
+		currentBackend := backend
+		stop := ss.stop
+		safe.Go(func() {
+			ss.execute(stop, currentBackendName, currentBackend)
+		})
+	}
+}
+
+// Stop stops all running ramps.
+func (ss *SlowStart) Stop() {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if ss.stop != nil {
+		close(ss.stop)
+		ss.stop = nil
+	}
+}
+
+func (ss *SlowStart) execute(stop chan bool, backendName string, backend *Backend) {
+	interval := backend.Duration / rampSteps
+	if interval <= 0 || !backend.rampUp(backendName, time.Now()) {
+		return
+	}
+	log.Debugf("Starting slow start for backend %s over %s", backendName, backend.Duration)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			log.Debugf("Stopping slow start for backend %s", backendName)
+			return
+		case <-ticker.C:
+			if !backend.rampUp(backendName, time.Now()) {
+				log.Debugf("Slow start done for backend %s", backendName)
+				return
+			}
+		}
+	}
+}
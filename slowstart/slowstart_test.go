@@ -0,0 +1,60 @@
+package slowstart
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWeight(t *testing.T) {
+	backend := &Backend{Duration: 10 * time.Second}
+	addedAt := time.Now()
+	cases := []struct {
+		desc     string
+		server   *server
+		now      time.Time
+		expected int
+	}{
+		{"not warming up", &server{weight: 2}, addedAt, 20},
+		{"just added", &server{weight: 2, addedAt: addedAt}, addedAt, 1},
+		{"half way", &server{weight: 2, addedAt: addedAt}, addedAt.Add(5 * time.Second), 10},
+		{"warmed up", &server{weight: 2, addedAt: addedAt}, addedAt.Add(time.Minute), 20},
+		{"zero weight", &server{weight: 0, addedAt: addedAt}, addedAt.Add(5 * time.Second), 0},
+	}
+
+	for _, c := range cases {
+		actual := backend.weight(c.server, c.now)
+		if actual != c.expected {
+			t.Fatalf("%s: expected %d, got %d", c.desc, c.expected, actual)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	ss := New()
+	oldURL, _ := url.Parse("http://10.0.0.1:80")
+	newURL, _ := url.Parse("http://10.0.0.2:80")
+
+	backend := ss.NewBackend("backend1", time.Hour)
+	if weight := backend.AddServer(oldURL, 1); weight != WeightScale {
+		t.Fatalf("server of a new backend should not be ramped up, got weight %d", weight)
+	}
+	ss.SetBackendsConfiguration(map[string]*Backend{"backend1": backend})
+
+	backend = ss.NewBackend("backend1", time.Hour)
+	if weight := backend.AddServer(newURL, 1); weight != 1 {
+		t.Fatalf("server added to a running backend should be ramped up, got weight %d", weight)
+	}
+	if weight := backend.AddServer(oldURL, 1); weight != WeightScale {
+		t.Fatalf("server already running should not be ramped up, got weight %d", weight)
+	}
+	ss.SetBackendsConfiguration(map[string]*Backend{"backend1": backend})
+	addedAt := backend.servers[newURL.String()].addedAt
+
+	backend = ss.NewBackend("backend1", time.Hour)
+	backend.AddServer(newURL, 1)
+	if !backend.servers[newURL.String()].addedAt.Equal(addedAt) {
+		t.Fatalf("ramp of a warming up server should survive reloads")
+	}
+	ss.Stop()
+}
@@ -0,0 +1,84 @@
+package tcp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Backend load balances connections between TCP servers with smooth weighted
+// round robin: a server of weight 2 receives twice as many connections as a
+// server of weight 1, without receiving them in a row.
+// The connections start with a PROXY protocol header of ProxyProtocolVersion
+// if not 0.
+type Backend struct {
+	Name                 string
+	ProxyProtocolVersion int
+	dialTimeout          time.Duration
+	lock                 sync.Mutex
+	servers              []*server
+}
+
+type server struct {
+	address       string
+	weight        int
+	currentWeight int
+}
+
+// NewBackend returns a new Backend without server, connecting to its servers
+// with the given dial timeout.
+func NewBackend(name string, dialTimeout time.Duration) *Backend {
+	return &Backend{Name: name, dialTimeout: dialTimeout}
+}
+
+// AddServer adds a server to the backend. A weight lower than 1 is set to 1.
+func (b *Backend) AddServer(address string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.servers = append(b.servers, &server{address: address, weight: weight})
+}
+
+// Dial connects to the next server of the backend, or to the following ones
+// if it can't be reached.
+func (b *Backend) Dial() (net.Conn, error) {
+	b.lock.Lock()
+	attempts := len(b.servers)
+	b.lock.Unlock()
+	if attempts == 0 {
+		return nil, errors.New("No server in backend " + b.Name)
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		address := b.next()
+		var conn net.Conn
+		conn, err = net.DialTimeout("tcp", address, b.dialTimeout)
+		if err == nil {
+			return conn, nil
+		}
+		log.Warnf("Error connecting to server %s of backend %s: %v", address, b.Name, err)
+	}
+	return nil, err
+}
+
+// next returns the address of the next server to connect to.
+func (b *Backend) next() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	totalWeight := 0
+	var best *server
+	for _, server := range b.servers {
+		server.currentWeight += server.weight
+		totalWeight += server.weight
+		if best == nil || server.currentWeight > best.currentWeight {
+			best = server
+		}
+	}
+	best.currentWeight -= totalWeight
+	return best.address
+}
@@ -0,0 +1,26 @@
+package tcp
+
+import (
+	"testing"
+)
+
+func TestBackendNext(t *testing.T) {
+	backend := NewBackend("backend1", 0)
+	backend.AddServer("10.0.0.1:5432", 2)
+	backend.AddServer("10.0.0.2:5432", 1)
+	backend.AddServer("10.0.0.3:5432", 0)
+
+	counts := map[string]int{}
+	previous := ""
+	for i := 0; i < 8; i++ {
+		address := backend.next()
+		if address == previous && address != "10.0.0.1:5432" {
+			t.Fatalf("Server %s should not be chosen twice in a row", address)
+		}
+		previous = address
+		counts[address]++
+	}
+	if counts["10.0.0.1:5432"] != 4 || counts["10.0.0.2:5432"] != 2 || counts["10.0.0.3:5432"] != 2 {
+		t.Fatalf("Unexpected distribution of the connections: %v", counts)
+	}
+}
@@ -0,0 +1,74 @@
+package tcp
+
+import (
+	"errors"
+	"strings"
+)
+
+// Frontend sends the connections it matches to a backend.
+// It matches the TLS connections requesting one of the SNI server names, which
+// can be wildcards like *.example.com. A frontend without SNI matches the
+// connections matching no other frontend of the entrypoint.
+// TLS connections are terminated with the certificates of the entrypoint,
+// unless Passthrough is set.
+type Frontend struct {
+	Name        string
+	SNI         []string
+	Passthrough bool
+	Backend     *Backend
+}
+
+// Router matches the connections of a TCP entrypoint with its frontends.
+type Router struct {
+	serverNames     map[string]*Frontend
+	defaultFrontend *Frontend
+}
+
+// NewRouter returns a new Router without frontend.
+func NewRouter() *Router {
+	return &Router{serverNames: make(map[string]*Frontend)}
+}
+
+// AddFrontend adds a frontend to the router. It returns an error if one of its
+// server names is already routed to another frontend.
+func (r *Router) AddFrontend(frontend *Frontend) error {
+	if len(frontend.SNI) == 0 {
+		if r.defaultFrontend != nil {
+			return errors.New("Frontend " + r.defaultFrontend.Name + " already matches the connections without SNI")
+		}
+		r.defaultFrontend = frontend
+		return nil
+	}
+	for _, serverName := range frontend.SNI {
+		serverName = strings.ToLower(serverName)
+		if existing, ok := r.serverNames[serverName]; ok {
+			return errors.New("Server name " + serverName + " is already routed to frontend " + existing.Name)
+		}
+	}
+	for _, serverName := range frontend.SNI {
+		r.serverNames[strings.ToLower(serverName)] = frontend
+	}
+	return nil
+}
+
+// Match returns the frontend of a connection requesting serverName, which is
+// empty for connections without SNI, or nil if no frontend matches.
+func (r *Router) Match(serverName string) *Frontend {
+	serverName = strings.ToLower(serverName)
+	if len(serverName) > 0 {
+		if frontend, ok := r.serverNames[serverName]; ok {
+			return frontend
+		}
+		if i := strings.Index(serverName, "."); i > 0 {
+			if frontend, ok := r.serverNames["*"+serverName[i:]]; ok {
+				return frontend
+			}
+		}
+	}
+	return r.defaultFrontend
+}
+
+// hasSNI returns whether the router has frontends matching server names.
+func (r *Router) hasSNI() bool {
+	return len(r.serverNames) > 0
+}
@@ -0,0 +1,50 @@
+package tcp
+
+import (
+	"testing"
+)
+
+func TestRouterMatch(t *testing.T) {
+	router := NewRouter()
+	db := &Frontend{Name: "db", SNI: []string{"db.example.com"}}
+	wildcard := &Frontend{Name: "wildcard", SNI: []string{"*.example.com"}}
+	defaultFrontend := &Frontend{Name: "default"}
+	for _, frontend := range []*Frontend{db, wildcard, defaultFrontend} {
+		if err := router.AddFrontend(frontend); err != nil {
+			t.Fatalf("Error adding frontend %s: %v", frontend.Name, err)
+		}
+	}
+
+	cases := []struct {
+		serverName string
+		expected   *Frontend
+	}{
+		{"db.example.com", db},
+		{"DB.Example.com", db},
+		{"mqtt.example.com", wildcard},
+		{"a.mqtt.example.com", defaultFrontend},
+		{"example.com", defaultFrontend},
+		{"", defaultFrontend},
+	}
+	for _, c := range cases {
+		actual := router.Match(c.serverName)
+		if actual != c.expected {
+			t.Fatalf("%q: expected frontend %s, got %+v", c.serverName, c.expected.Name, actual)
+		}
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	router := NewRouter()
+	router.AddFrontend(&Frontend{Name: "db", SNI: []string{"db.example.com"}})
+	router.AddFrontend(&Frontend{Name: "default"})
+	if err := router.AddFrontend(&Frontend{Name: "db2", SNI: []string{"db.example.com"}}); err == nil {
+		t.Fatalf("Server name routed to two frontends should return an error")
+	}
+	if err := router.AddFrontend(&Frontend{Name: "default2"}); err == nil {
+		t.Fatalf("Two frontends without SNI should return an error")
+	}
+	if router.Match("") == nil || router.Match("").Name != "default" {
+		t.Fatalf("Conflicting frontend should not replace the existing one")
+	}
+}
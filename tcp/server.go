@@ -0,0 +1,259 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/proxyproto"
+	"github.com/containous/traefik/safe"
+)
+
+// clientHelloTimeout is the maximum time waiting for the TLS ClientHello of a
+// connection, to read the server name it requests.
+const clientHelloTimeout = 5 * time.Second
+
+// recordTypeHandshake is the first byte of a TLS connection.
+const recordTypeHandshake = 0x16
+
+var errClientHelloRead = errors.New("ClientHello read")
+
+// Server proxies the connections of a TCP entrypoint to the backends of the
+// frontends matched by its router, which can be updated on configuration
+// reloads without closing the listener.
+type Server struct {
+	Addr      string
+	tlsConfig *tls.Config
+	router    safe.Safe
+	lock      sync.Mutex
+	listener  net.Listener
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// NewServer returns a new Server listening on addr, terminating TLS with
+// tlsConfig if not nil.
+func NewServer(addr string, tlsConfig *tls.Config) *Server {
+	server := &Server{
+		Addr:      addr,
+		tlsConfig: tlsConfig,
+		conns:     make(map[net.Conn]struct{}),
+	}
+	server.router.Set(NewRouter())
+	return server
+}
+
+// UpdateRouter sets the router of the new connections.
+func (s *Server) UpdateRouter(router *Router) {
+	s.router.Set(router)
+}
+
+// ListenAndServe listens on the address of the server and serves the
+// connections until the server is closed.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves the connections of listener until the server is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return listener.Close()
+	}
+	s.listener = listener
+	s.lock.Unlock()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				log.Warnf("Error accepting connection on %s: %v", s.Addr, err)
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		s.track(conn, true)
+		safe.Go(func() {
+			defer s.track(conn, false)
+			s.serveConn(conn)
+		})
+	}
+}
+
+// Close stops listening and closes the current connections.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+func (s *Server) track(conn net.Conn, add bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if add {
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	router := s.router.Get().(*Router)
+	var serverName string
+	var isTLS bool
+	if router.hasSNI() || s.tlsConfig != nil {
+		var err error
+		serverName, isTLS, conn, err = peekClientHello(conn)
+		if err != nil {
+			log.Debugf("Error reading connection from %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+	frontend := router.Match(serverName)
+	if frontend == nil {
+		log.Debugf("No TCP frontend on %s for connection from %s requesting %q", s.Addr, conn.RemoteAddr(), serverName)
+		return
+	}
+	if isTLS && !frontend.Passthrough && s.tlsConfig != nil {
+		conn = tls.Server(conn, s.tlsConfig)
+	}
+	backendConn, err := frontend.Backend.Dial()
+	if err != nil {
+		log.Errorf("Error connecting to backend %s of frontend %s: %v", frontend.Backend.Name, frontend.Name, err)
+		return
+	}
+	defer backendConn.Close()
+	if frontend.Backend.ProxyProtocolVersion != 0 {
+		header, err := proxyproto.NewHeader(frontend.Backend.ProxyProtocolVersion, conn.RemoteAddr(), conn.LocalAddr())
+		if err == nil {
+			_, err = header.WriteTo(backendConn)
+		}
+		if err != nil {
+			log.Errorf("Error sending PROXY protocol header to backend %s of frontend %s: %v", frontend.Backend.Name, frontend.Name, err)
+			return
+		}
+	}
+	proxy(conn, backendConn)
+}
+
+// peekClientHello reads the server name requested by the TLS ClientHello of a
+// connection, if any, and returns a connection replaying what was read.
+func peekClientHello(conn net.Conn) (string, bool, net.Conn, error) {
+	conn.SetReadDeadline(time.Now().Add(clientHelloTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+	reader := bufio.NewReader(conn)
+	first, err := reader.Peek(1)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			// the server speaks first
+			return "", false, &peekedConn{Conn: conn, reader: reader}, nil
+		}
+		return "", false, conn, err
+	}
+	if first[0] != recordTypeHandshake {
+		return "", false, &peekedConn{Conn: conn, reader: reader}, nil
+	}
+	// let the TLS library parse the ClientHello, and abort the handshake
+	// once the certificate of the server name is requested
+	var buffer bytes.Buffer
+	var serverName string
+	helloConn := &helloConn{Conn: conn, reader: io.TeeReader(reader, &buffer)}
+	tls.Server(helloConn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			serverName = hello.ServerName
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+	return serverName, true, &peekedConn{Conn: conn, reader: io.MultiReader(&buffer, reader)}, nil
+}
+
+// helloConn is a read-only connection used to parse a ClientHello.
+type helloConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (hc *helloConn) Read(b []byte) (int, error) {
+	return hc.reader.Read(b)
+}
+
+func (hc *helloConn) Write(b []byte) (int, error) {
+	return 0, errClientHelloRead
+}
+
+// peekedConn is a connection whose first bytes have already been read.
+type peekedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (pc *peekedConn) Read(b []byte) (int, error) {
+	return pc.reader.Read(b)
+}
+
+func (pc *peekedConn) CloseWrite() error {
+	if conn, ok := pc.Conn.(closeWriter); ok {
+		return conn.CloseWrite()
+	}
+	return pc.Conn.Close()
+}
+
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// proxy copies the data between the client and the backend connections in
+// both directions, until both of them are done.
+func proxy(client net.Conn, backend net.Conn) {
+	done := make(chan bool, 2)
+	safe.Go(func() {
+		pipe(backend, client)
+		done <- true
+	})
+	safe.Go(func() {
+		pipe(client, backend)
+		done <- true
+	})
+	<-done
+	<-done
+}
+
+// pipe copies src to dst, and closes dst for writing when src is done.
+func pipe(dst net.Conn, src net.Conn) {
+	if _, err := io.Copy(dst, src); err != nil {
+		log.Debugf("Error proxying connection from %s: %v", src.RemoteAddr(), err)
+	}
+	if conn, ok := dst.(closeWriter); ok {
+		conn.CloseWrite()
+	} else {
+		dst.Close()
+	}
+}
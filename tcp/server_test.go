@@ -0,0 +1,171 @@
+package tcp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// startEchoServer starts a TCP server writing back what it reads.
+func startEchoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener
+}
+
+// startProxy starts a TCP server with the given frontends.
+func startProxy(t *testing.T, tlsConfig *tls.Config, frontends ...*Frontend) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	server := NewServer(listener.Addr().String(), tlsConfig)
+	router := NewRouter()
+	for _, frontend := range frontends {
+		if err := router.AddFrontend(frontend); err != nil {
+			t.Fatalf("Error adding frontend %s: %v", frontend.Name, err)
+		}
+	}
+	server.UpdateRouter(router)
+	go server.Serve(listener)
+	return server
+}
+
+func newTestBackend(address string) *Backend {
+	backend := NewBackend("backend", 0)
+	backend.AddServer(address, 1)
+	return backend
+}
+
+func echo(t *testing.T, conn net.Conn, message string) {
+	if _, err := conn.Write([]byte(message + "\n")); err != nil {
+		t.Fatalf("Error writing: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Error reading: %v", err)
+	}
+	if line != message+"\n" {
+		t.Fatalf("Expected %q, got %q", message, line)
+	}
+}
+
+func TestServerProxy(t *testing.T) {
+	echoServer := startEchoServer(t)
+	defer echoServer.Close()
+	proxy := startProxy(t, nil, &Frontend{Name: "default", Backend: newTestBackend(echoServer.Addr().String())})
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Addr)
+	if err != nil {
+		t.Fatalf("Error connecting to the proxy: %v", err)
+	}
+	defer conn.Close()
+	echo(t, conn, "hello")
+}
+
+func TestServerSNIPassthrough(t *testing.T) {
+	echoServer := startEchoServer(t)
+	defer echoServer.Close()
+	httpsServer := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("https backend"))
+	}))
+	defer httpsServer.Close()
+	proxy := startProxy(t, nil,
+		&Frontend{Name: "web", SNI: []string{"web.example.com"}, Passthrough: true, Backend: newTestBackend(httpsServer.Listener.Addr().String())},
+		&Frontend{Name: "default", Backend: newTestBackend(echoServer.Addr().String())},
+	)
+	defer proxy.Close()
+
+	conn, err := tls.Dial("tcp", proxy.Addr, &tls.Config{ServerName: "web.example.com", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Error connecting to the proxy: %v", err)
+	}
+	defer conn.Close()
+	req, _ := http.NewRequest("GET", "https://web.example.com/", nil)
+	req.Write(conn)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatalf("Error reading response: %v", err)
+	}
+	body := make([]byte, len("https backend"))
+	io.ReadFull(resp.Body, body)
+	if string(body) != "https backend" {
+		t.Fatalf("Expected the response of the https backend, got %q", body)
+	}
+
+	plainConn, err := net.Dial("tcp", proxy.Addr)
+	if err != nil {
+		t.Fatalf("Error connecting to the proxy: %v", err)
+	}
+	defer plainConn.Close()
+	echo(t, plainConn, "hello")
+}
+
+func TestServerTLSTermination(t *testing.T) {
+	echoServer := startEchoServer(t)
+	defer echoServer.Close()
+	// borrow the certificate of an httptest server
+	httpsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer httpsServer.Close()
+	tlsConfig := &tls.Config{Certificates: httpsServer.TLS.Certificates}
+	proxy := startProxy(t, tlsConfig, &Frontend{Name: "db", SNI: []string{"db.example.com"}, Backend: newTestBackend(echoServer.Addr().String())})
+	defer proxy.Close()
+
+	conn, err := tls.Dial("tcp", proxy.Addr, &tls.Config{ServerName: "db.example.com", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Error connecting to the proxy: %v", err)
+	}
+	defer conn.Close()
+	echo(t, conn, "hello")
+
+	unknown, err := tls.Dial("tcp", proxy.Addr, &tls.Config{ServerName: "unknown.example.com", InsecureSkipVerify: true})
+	if err == nil {
+		unknown.Close()
+		t.Fatalf("Connection matching no frontend should be closed")
+	}
+}
+
+func TestServerProxyProtocol(t *testing.T) {
+	echoServer := startEchoServer(t)
+	defer echoServer.Close()
+	backend := newTestBackend(echoServer.Addr().String())
+	backend.ProxyProtocolVersion = 1
+	proxy := startProxy(t, nil, &Frontend{Name: "default", Backend: backend})
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Addr)
+	if err != nil {
+		t.Fatalf("Error connecting to the proxy: %v", err)
+	}
+	defer conn.Close()
+	// the echo server sends the header back
+	reader := bufio.NewReader(conn)
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Error reading: %v", err)
+	}
+	expected := fmt.Sprintf("PROXY TCP4 127.0.0.1 127.0.0.1 %d %s\r\n", conn.LocalAddr().(*net.TCPAddr).Port, proxy.Addr[len("127.0.0.1:"):])
+	if header != expected {
+		t.Fatalf("Expected header %q, got %q", expected, header)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/proxyproto"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	"golang.org/x/net/http2"
+)
+
+const (
+	defaultDialTimeout         = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// proxyProtocolMaxClients is the maximum number of client connections whose
+// transport is kept by a proxyProtocolTransport.
+const proxyProtocolMaxClients = 1024
+
+// h2cScheme is the scheme of the URLs of the servers speaking HTTP/2 without
+// TLS.
+const h2cScheme = "h2c://"
+
+// backendTransports holds the transports of the backends with their own
+// transport settings or h2c servers, the other ones using the default
+// transport.
+// A transport is kept across configuration reloads as long as the settings of
+// its backend don't change, so that its idle connections are reused.
+type backendTransports struct {
+	maxIdleConnsPerHost int
+	lock                sync.Mutex
+	current             map[string]*backendTransport
+	loading             map[string]*backendTransport
+}
+
+type backendTransport struct {
+	configuration types.Transport
+	h2c           bool
+	transport     http.RoundTripper
+	stop          chan bool
+}
+
+type idleConnectionsCloser interface {
+	CloseIdleConnections()
+}
+
+func newBackendTransports(maxIdleConnsPerHost int) *backendTransports {
+	return &backendTransports{
+		maxIdleConnsPerHost: maxIdleConnsPerHost,
+		current:             make(map[string]*backendTransport),
+		loading:             make(map[string]*backendTransport),
+	}
+}
+
+// get returns the round tripper to the servers of a backend of the
+// configuration being loaded.
+func (bt *backendTransports) get(backendName string, backend *types.Backend) (http.RoundTripper, error) {
+	if backend == nil {
+		return http.DefaultTransport, nil
+	}
+	h2c, err := isH2CBackend(backend)
+	if err != nil {
+		return nil, err
+	}
+	if backend.Transport == nil && !h2c {
+		return http.DefaultTransport, nil
+	}
+	configuration := types.Transport{}
+	if backend.Transport != nil {
+		configuration = *backend.Transport
+	}
+	bt.lock.Lock()
+	defer bt.lock.Unlock()
+	if transport, ok := bt.loading[backendName]; ok {
+		return transport.transport, nil
+	}
+	if transport, ok := bt.current[backendName]; ok && transport.h2c == h2c && reflect.DeepEqual(transport.configuration, configuration) {
+		bt.loading[backendName] = transport
+		return transport.transport, nil
+	}
+	transport, err := newBackendTransport(configuration, h2c, bt.maxIdleConnsPerHost)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Creating transport for backend %s", backendName)
+	bt.loading[backendName] = transport
+	return transport.transport, nil
+}
+
+// commit switches to the transports of the loaded configuration, and closes
+// the idle connections of the transports which are not used anymore.
+func (bt *backendTransports) commit() {
+	bt.lock.Lock()
+	defer bt.lock.Unlock()
+	for backendName, transport := range bt.current {
+		if bt.loading[backendName] != transport {
+			transport.close()
+		}
+	}
+	bt.current = bt.loading
+	bt.loading = make(map[string]*backendTransport)
+}
+
+// isH2CBackend returns whether the servers of a backend speak HTTP/2 without
+// TLS, which must be the case of all of them or none.
+func isH2CBackend(backend *types.Backend) (bool, error) {
+	h2cServers := 0
+	for _, server := range backend.Servers {
+		if strings.HasPrefix(strings.ToLower(server.URL), h2cScheme) {
+			h2cServers++
+		}
+	}
+	if h2cServers > 0 && h2cServers < len(backend.Servers) {
+		return false, errors.New("h2c and non h2c servers can't be mixed in a backend")
+	}
+	return h2cServers > 0, nil
+}
+
+func newBackendTransport(configuration types.Transport, h2c bool, maxIdleConnsPerHost int) (*backendTransport, error) {
+	dialer := &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if len(configuration.DialTimeout) > 0 {
+		dialTimeout, err := time.ParseDuration(configuration.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		dialer.Timeout = dialTimeout
+	}
+	if configuration.ProxyProtocolVersion != 0 {
+		if h2c {
+			return nil, errors.New("PROXY protocol can't be sent to h2c servers")
+		}
+		if err := proxyproto.CheckVersion(configuration.ProxyProtocolVersion); err != nil {
+			return nil, err
+		}
+	}
+	if h2c {
+		return newH2CTransport(configuration, dialer)
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		Dial:                  dialer.Dial,
+		TLSHandshakeTimeout:   defaultTLSHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+	}
+	if len(configuration.ResponseHeaderTimeout) > 0 {
+		responseHeaderTimeout, err := time.ParseDuration(configuration.ResponseHeaderTimeout)
+		if err != nil {
+			return nil, err
+		}
+		transport.ResponseHeaderTimeout = responseHeaderTimeout
+	}
+	if configuration.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = configuration.MaxIdleConnsPerHost
+	}
+	if configuration.TLS != nil {
+		tlsConfig, err := configuration.TLS.CreateTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	var roundTripper http.RoundTripper = transport
+	if configuration.ProxyProtocolVersion != 0 {
+		roundTripper = &proxyProtocolTransport{
+			version:    configuration.ProxyProtocolVersion,
+			dialer:     dialer,
+			template:   transport,
+			transports: make(map[string]*http.Transport),
+		}
+	}
+	return startBackendTransport(&backendTransport{
+		configuration: configuration,
+		transport:     roundTripper,
+		stop:          make(chan bool),
+	})
+}
+
+// proxyProtocolTransport sends the requests of each client connection on
+// their own connections to the servers, starting with a PROXY protocol header
+// from the client to the server.
+// The transports of the client connections are kept, so that a connection to
+// a server is reused by the next requests of the same client connection.
+type proxyProtocolTransport struct {
+	version    int
+	dialer     *net.Dialer
+	template   *http.Transport
+	lock       sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func (pt *proxyProtocolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return pt.clientTransport(req.RemoteAddr).RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the kept transports,
+// and forgets them.
+func (pt *proxyProtocolTransport) CloseIdleConnections() {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	pt.closeTransports()
+}
+
+func (pt *proxyProtocolTransport) closeTransports() {
+	for _, transport := range pt.transports {
+		transport.CloseIdleConnections()
+	}
+	pt.transports = make(map[string]*http.Transport)
+}
+
+// clientTransport returns the transport of a client connection, creating it
+// if needed. When too many transports are kept, they are all closed first.
+func (pt *proxyProtocolTransport) clientTransport(remoteAddr string) *http.Transport {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	if transport, ok := pt.transports[remoteAddr]; ok {
+		return transport
+	}
+	if len(pt.transports) >= proxyProtocolMaxClients {
+		pt.closeTransports()
+	}
+	transport := pt.newClientTransport(remoteAddr)
+	pt.transports[remoteAddr] = transport
+	return transport
+}
+
+func (pt *proxyProtocolTransport) newClientTransport(remoteAddr string) *http.Transport {
+	// the requests sent by traefik itself have no client address
+	var source net.Addr
+	if clientAddr, err := net.ResolveTCPAddr("tcp", remoteAddr); err == nil {
+		source = clientAddr
+	}
+	return &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			conn, err := pt.dialer.Dial(network, addr)
+			if err != nil {
+				return nil, err
+			}
+			header, err := proxyproto.NewHeader(pt.version, source, conn.RemoteAddr())
+			if err == nil {
+				_, err = header.WriteTo(conn)
+			}
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		},
+		TLSClientConfig:       pt.template.TLSClientConfig,
+		TLSHandshakeTimeout:   pt.template.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: pt.template.ResponseHeaderTimeout,
+		ExpectContinueTimeout: pt.template.ExpectContinueTimeout,
+		// the requests of a client connection are sent one at a time
+		MaxIdleConnsPerHost: 1,
+	}
+}
+
+// newH2CTransport returns a transport speaking HTTP/2 without TLS to the
+// servers, over a single connection per server.
+func newH2CTransport(configuration types.Transport, dialer *net.Dialer) (*backendTransport, error) {
+	if configuration.TLS != nil {
+		return nil, errors.New("TLS can't be configured with h2c servers")
+	}
+	if len(configuration.ResponseHeaderTimeout) > 0 {
+		log.Warnf("Response header timeout is not supported with h2c servers")
+	}
+	if configuration.MaxIdleConnsPerHost != 0 {
+		log.Warnf("Maximum idle connections per host is not supported with h2c servers")
+	}
+	transport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		},
+	}
+	return startBackendTransport(&backendTransport{
+		configuration: configuration,
+		h2c:           true,
+		transport:     transport,
+		stop:          make(chan bool),
+	})
+}
+
+// startBackendTransport starts closing the idle connections of the transport
+// periodically if an idle connection timeout is set.
+func startBackendTransport(backendTransport *backendTransport) (*backendTransport, error) {
+	configuration := backendTransport.configuration
+	if len(configuration.IdleConnTimeout) > 0 {
+		idleConnTimeout, err := time.ParseDuration(configuration.IdleConnTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if idleConnTimeout > 0 {
+			safe.Go(func() {
+				backendTransport.closeIdleConnections(idleConnTimeout)
+			})
+		}
+	}
+	return backendTransport, nil
+}
+
+// closeIdleConnections closes the idle connections every idleConnTimeout,
+// until the transport is closed.
+func (bt *backendTransport) closeIdleConnections(idleConnTimeout time.Duration) {
+	ticker := time.NewTicker(idleConnTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-bt.stop:
+			return
+		case <-ticker.C:
+			bt.closeIdle()
+		}
+	}
+}
+
+func (bt *backendTransport) closeIdle() {
+	if closer, ok := bt.transport.(idleConnectionsCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func (bt *backendTransport) close() {
+	close(bt.stop)
+	bt.closeIdle()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/proxyproto"
+	"github.com/containous/traefik/types"
+	"golang.org/x/net/http2"
+)
+
+func TestBackendTransports(t *testing.T) {
+	transports := newBackendTransports(100)
+
+	roundTripper, err := transports.get("backend1", &types.Backend{})
+	if err != nil {
+		t.Fatalf("Error getting transport: %v", err)
+	}
+	if roundTripper != http.DefaultTransport {
+		t.Fatalf("Backend without transport settings should use the default transport")
+	}
+
+	backend := &types.Backend{Transport: &types.Transport{
+		DialTimeout:           "5s",
+		ResponseHeaderTimeout: "10s",
+		MaxIdleConnsPerHost:   10,
+		TLS:                   &types.ClientTLS{InsecureSkipVerify: true, ServerName: "backend.local"},
+	}}
+	roundTripper, err = transports.get("backend2", backend)
+	if err != nil {
+		t.Fatalf("Error getting transport: %v", err)
+	}
+	transport := roundTripper.(*http.Transport)
+	if transport.ResponseHeaderTimeout != 10*time.Second || transport.MaxIdleConnsPerHost != 10 {
+		t.Fatalf("Unexpected transport settings: %+v", transport)
+	}
+	if !transport.TLSClientConfig.InsecureSkipVerify || transport.TLSClientConfig.ServerName != "backend.local" {
+		t.Fatalf("Unexpected TLS settings: %+v", transport.TLSClientConfig)
+	}
+	transports.commit()
+
+	reloaded, _ := transports.get("backend2", &types.Backend{Transport: &types.Transport{
+		DialTimeout:           "5s",
+		ResponseHeaderTimeout: "10s",
+		MaxIdleConnsPerHost:   10,
+		TLS:                   &types.ClientTLS{InsecureSkipVerify: true, ServerName: "backend.local"},
+	}})
+	if reloaded != roundTripper {
+		t.Fatalf("Transport should be kept across reloads when its settings don't change")
+	}
+	transports.commit()
+
+	changed, _ := transports.get("backend2", &types.Backend{Transport: &types.Transport{ResponseHeaderTimeout: "20s"}})
+	if changed == roundTripper {
+		t.Fatalf("Transport should be recreated when its settings change")
+	}
+	if changed.(*http.Transport).MaxIdleConnsPerHost != 100 {
+		t.Fatalf("Transport should default to the global maximum idle connections per host")
+	}
+	transports.commit()
+
+	if _, err := transports.get("backend3", &types.Backend{Transport: &types.Transport{DialTimeout: "foo"}}); err == nil {
+		t.Fatalf("Invalid dial timeout should return an error")
+	}
+}
+
+func TestBackendTransportsH2C(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				rw.Write([]byte(r.Proto))
+			})})
+		}
+	}()
+
+	transports := newBackendTransports(100)
+	roundTripper, err := transports.get("backend1", &types.Backend{Servers: map[string]types.Server{
+		"server1": {URL: "h2c://" + listener.Addr().String()},
+	}})
+	if err != nil {
+		t.Fatalf("Error getting transport: %v", err)
+	}
+	if _, ok := roundTripper.(*http2.Transport); !ok {
+		t.Fatalf("Backend with h2c servers should use an HTTP/2 transport, got %T", roundTripper)
+	}
+	serverURL, _ := parseServerURL("h2c://" + listener.Addr().String())
+	req, _ := http.NewRequest("GET", serverURL.String(), nil)
+	res, err := roundTripper.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Fatalf("Expected an HTTP/2 request, got %q", body)
+	}
+	transports.commit()
+
+	if _, err := transports.get("backend2", &types.Backend{Servers: map[string]types.Server{
+		"server1": {URL: "h2c://127.0.0.1:50051"},
+		"server2": {URL: "http://127.0.0.1:80"},
+	}}); err == nil {
+		t.Fatalf("Mixing h2c and non h2c servers should return an error")
+	}
+	if _, err := transports.get("backend3", &types.Backend{
+		Servers:   map[string]types.Server{"server1": {URL: "h2c://127.0.0.1:50051"}},
+		Transport: &types.Transport{TLS: &types.ClientTLS{InsecureSkipVerify: true}},
+	}); err == nil {
+		t.Fatalf("TLS settings with h2c servers should return an error")
+	}
+}
+
+func TestBackendTransportsProxyProtocol(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	proxyListener, _ := proxyproto.NewListener(listener, []string{"127.0.0.1"})
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(r.RemoteAddr))
+	}))
+	server.Listener = proxyListener
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	transports := newBackendTransports(100)
+	roundTripper, err := transports.get("backend1", &types.Backend{Transport: &types.Transport{ProxyProtocolVersion: 2}})
+	if err != nil {
+		t.Fatalf("Error getting transport: %v", err)
+	}
+	for _, remoteAddr := range []string{"192.168.0.1:56324", "192.168.0.1:56324", "192.168.0.2:41000"} {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.RemoteAddr = remoteAddr
+		res, err := roundTripper.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Error sending request: %v", err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != remoteAddr {
+			t.Fatalf("Expected the server to see the client address %s, got %q", remoteAddr, body)
+		}
+	}
+	// the connection of the first client is reused by its second request
+	if connections := atomic.LoadInt32(&connections); connections != 2 {
+		t.Fatalf("Expected 2 connections to the server, got %d", connections)
+	}
+
+	if _, err := transports.get("backend2", &types.Backend{Transport: &types.Transport{ProxyProtocolVersion: 3}}); err == nil {
+		t.Fatalf("Invalid PROXY protocol version should return an error")
+	}
+}
@@ -1,18 +1,49 @@
 package types
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/ryanuber/go-glob"
+	"io/ioutil"
+	"os"
 	"strings"
 )
 
 // Backend holds backend configuration.
 type Backend struct {
-	Servers        map[string]Server `json:"servers,omitempty"`
-	CircuitBreaker *CircuitBreaker   `json:"circuitBreaker,omitempty"`
-	LoadBalancer   *LoadBalancer     `json:"loadBalancer,omitempty"`
-	MaxConn        *MaxConn          `json:"maxConn,omitempty"`
+	Servers          map[string]Server `json:"servers,omitempty"`
+	CircuitBreaker   *CircuitBreaker   `json:"circuitBreaker,omitempty"`
+	LoadBalancer     *LoadBalancer     `json:"loadBalancer,omitempty"`
+	MaxConn          *MaxConn          `json:"maxConn,omitempty"`
+	HealthCheck      *HealthCheck      `json:"healthCheck,omitempty"`
+	Retry            *Retry            `json:"retry,omitempty"`
+	OutlierDetection *OutlierDetection `json:"outlierDetection,omitempty"`
+	Transport        *Transport        `json:"transport,omitempty"`
+}
+
+// Transport holds the settings of the connections to the servers of a
+// backend.
+type Transport struct {
+	DialTimeout           string     `json:"dialTimeout,omitempty"`
+	ResponseHeaderTimeout string     `json:"responseHeaderTimeout,omitempty"`
+	IdleConnTimeout       string     `json:"idleConnTimeout,omitempty"`
+	MaxIdleConnsPerHost   int        `json:"maxIdleConnsPerHost,omitempty"`
+	TLS                   *ClientTLS `json:"tls,omitempty"`
+	ProxyProtocolVersion  int        `json:"proxyProtocolVersion,omitempty"`
+}
+
+// Retry holds the retry configuration of a backend, overriding the global one.
+type Retry struct {
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// OutlierDetection holds the passive health check configuration of a backend.
+type OutlierDetection struct {
+	ConsecutiveErrors int    `json:"consecutiveErrors,omitempty"`
+	BaseEjectionTime  string `json:"baseEjectionTime,omitempty"`
+	MaxEjectionTime   string `json:"maxEjectionTime,omitempty"`
 }
 
 // MaxConn holds maximum connection configuration
@@ -21,9 +52,22 @@ type MaxConn struct {
 	ExtractorFunc string `json:"extractorFunc,omitempty"`
 }
 
+// HealthCheck holds health check configuration.
+type HealthCheck struct {
+	Path           string `json:"path,omitempty"`
+	Interval       string `json:"interval,omitempty"`
+	Timeout        string `json:"timeout,omitempty"`
+	ExpectedStatus int    `json:"expectedStatus,omitempty"`
+}
+
 // LoadBalancer holds load balancing configuration.
+// HashKey is the request key of the ConsistentHash method: client.ip
+// (default), request.host, request.header.<name> or request.cookie.<name>.
 type LoadBalancer struct {
-	Method string `json:"method,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Sticky    bool   `json:"sticky,omitempty"`
+	HashKey   string `json:"hashKey,omitempty"`
+	SlowStart string `json:"slowStart,omitempty"`
 }
 
 // CircuitBreaker holds circuit breaker configuration.
@@ -49,6 +93,123 @@ type Frontend struct {
 	Routes         map[string]Route `json:"routes,omitempty"`
 	PassHostHeader bool             `json:"passHostHeader,omitempty"`
 	Priority       int              `json:"priority"`
+	BasicAuth      *BasicAuth       `json:"basicAuth,omitempty"`
+	DigestAuth     *DigestAuth      `json:"digestAuth,omitempty"`
+	ForwardAuth    *ForwardAuth     `json:"forwardAuth,omitempty"`
+	RateLimit      *RateLimit       `json:"rateLimit,omitempty"`
+	// WhitelistSourceRange restricts the frontend to client IPs in these
+	// CIDRs. The client IP is read from X-Forwarded-For at the given depth
+	// (1 being the rightmost address), or from the connection if 0.
+	WhitelistSourceRange        []string `json:"whitelistSourceRange,omitempty"`
+	WhitelistXForwardedForDepth int      `json:"whitelistXForwardedForDepth,omitempty"`
+	Headers                     *Headers `json:"headers,omitempty"`
+	CORS                        *CORS    `json:"cors,omitempty"`
+	Compress                    bool     `json:"compress,omitempty"`
+	// Errors replaces the body of the responses with these statuses by a
+	// page served by another backend.
+	Errors map[string]*ErrorPage `json:"errors,omitempty"`
+	Mirror *Mirror               `json:"mirror,omitempty"`
+	// Split sends the requests to several backends instead of Backend.
+	Split *Split `json:"split,omitempty"`
+}
+
+// Split holds a weighted traffic split between backends, for canary releases.
+// Backends maps the name of each backend to its weight. If Sticky is set, a
+// client keeps being sent to the same backend with a cookie.
+type Split struct {
+	Backends map[string]int `json:"backends,omitempty"`
+	Sticky   bool           `json:"sticky,omitempty"`
+}
+
+// Mirror holds a traffic mirroring configuration: Percent of the requests
+// (all of them if unset) are copied to the Backend, whose responses are
+// discarded. Requests with a body bigger than MaxBodySize bytes are not
+// mirrored.
+type Mirror struct {
+	Backend     string `json:"backend,omitempty"`
+	Percent     *int   `json:"percent,omitempty"`
+	MaxBodySize int64  `json:"maxBodySize,omitempty"`
+}
+
+// ErrorPage holds a custom error page configuration.
+// Status is a list of status codes or ranges (like 500-599), Query is the
+// path requested to the backend, where {status} is replaced by the status
+// code of the original response.
+type ErrorPage struct {
+	Status  []string `json:"status,omitempty"`
+	Backend string   `json:"backend,omitempty"`
+	Query   string   `json:"query,omitempty"`
+}
+
+// CORS holds cross-origin resource sharing configuration.
+// AllowedOrigins may contain wildcards, like https://*.example.com or *.
+type CORS struct {
+	AllowedOrigins   []string `json:"allowedOrigins,omitempty"`
+	AllowedMethods   []string `json:"allowedMethods,omitempty"`
+	AllowedHeaders   []string `json:"allowedHeaders,omitempty"`
+	ExposedHeaders   []string `json:"exposedHeaders,omitempty"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty"`
+	MaxAge           int      `json:"maxAge,omitempty"`
+}
+
+// Headers holds the custom and security headers of a frontend.
+// A custom header with an empty value is removed from the request or the
+// response.
+type Headers struct {
+	CustomRequestHeaders  map[string]string `json:"customRequestHeaders,omitempty"`
+	CustomResponseHeaders map[string]string `json:"customResponseHeaders,omitempty"`
+
+	AllowedHosts            []string `json:"allowedHosts,omitempty"`
+	SSLRedirect             bool     `json:"sslRedirect,omitempty"`
+	SSLHost                 string   `json:"sslHost,omitempty"`
+	STSSeconds              int64    `json:"stsSeconds,omitempty"`
+	STSIncludeSubdomains    bool     `json:"stsIncludeSubdomains,omitempty"`
+	STSPreload              bool     `json:"stsPreload,omitempty"`
+	FrameDeny               bool     `json:"frameDeny,omitempty"`
+	CustomFrameOptionsValue string   `json:"customFrameOptionsValue,omitempty"`
+	ContentTypeNosniff      bool     `json:"contentTypeNosniff,omitempty"`
+	BrowserXSSFilter        bool     `json:"browserXssFilter,omitempty"`
+	ContentSecurityPolicy   string   `json:"contentSecurityPolicy,omitempty"`
+}
+
+// RateLimit holds rate limiting configuration.
+// Requests are grouped by ExtractorFunc (as for MaxConn) and must fit in
+// every rate of the RateSet.
+type RateLimit struct {
+	RateSet       map[string]*Rate `json:"rateSet,omitempty"`
+	ExtractorFunc string           `json:"extractorFunc,omitempty"`
+}
+
+// Rate holds a rate limiting window: Average requests per Period, with
+// bursts up to Burst requests.
+type Rate struct {
+	Period  string `json:"period,omitempty"`
+	Average int64  `json:"average,omitempty"`
+	Burst   int64  `json:"burst,omitempty"`
+}
+
+// BasicAuth holds HTTP basic authentication configuration.
+// Users are htpasswd entries (user:hash), given inline or in UsersFile.
+type BasicAuth struct {
+	Users     []string `json:"users,omitempty"`
+	UsersFile string   `json:"usersFile,omitempty"`
+	Realm     string   `json:"realm,omitempty"`
+}
+
+// ForwardAuth holds forward authentication configuration.
+// AuthResponseHeaders are copied from the authentication service answer
+// onto the forwarded request.
+type ForwardAuth struct {
+	Address             string   `json:"address,omitempty"`
+	AuthResponseHeaders []string `json:"authResponseHeaders,omitempty"`
+}
+
+// DigestAuth holds HTTP digest authentication configuration.
+// Users are htdigest entries (user:realm:hash), given inline or in UsersFile.
+type DigestAuth struct {
+	Users     []string `json:"users,omitempty"`
+	UsersFile string   `json:"usersFile,omitempty"`
+	Realm     string   `json:"realm,omitempty"`
 }
 
 // LoadBalancerMethod holds the method of load balancing to use.
@@ -59,11 +220,17 @@ const (
 	Wrr LoadBalancerMethod = iota
 	// Drr = Dynamic Round Robin
 	Drr
+	// LeastConn = Least Connections
+	LeastConn
+	// ConsistentHash = Consistent Hashing of a request key
+	ConsistentHash
 )
 
 var loadBalancerMethodNames = []string{
 	"Wrr",
 	"Drr",
+	"LeastConn",
+	"ConsistentHash",
 }
 
 // NewLoadBalancerMethod create a new LoadBalancerMethod from a given LoadBalancer.
@@ -83,8 +250,71 @@ var ErrInvalidLoadBalancerMethod = errors.New("Invalid method, using default")
 
 // Configuration of a provider.
 type Configuration struct {
-	Backends  map[string]*Backend  `json:"backends,omitempty"`
-	Frontends map[string]*Frontend `json:"frontends,omitempty"`
+	Backends        map[string]*Backend     `json:"backends,omitempty"`
+	Frontends       map[string]*Frontend    `json:"frontends,omitempty"`
+	TCPBackends     map[string]*TCPBackend  `json:"tcpBackends,omitempty"`
+	TCPFrontends    map[string]*TCPFrontend `json:"tcpFrontends,omitempty"`
+	UDPBackends     map[string]*UDPBackend  `json:"udpBackends,omitempty"`
+	UDPFrontends    map[string]*UDPFrontend `json:"udpFrontends,omitempty"`
+	TLSCertificates []*TLSCertificate       `json:"tlsCertificates,omitempty"`
+}
+
+// TLSCertificate holds a certificate served on the TLS entrypoints, or on all
+// of them if EntryPoints is empty, for the server names it is valid for.
+// CertFile and KeyFile are either file paths or PEM contents. The key is
+// removed from the configurations exposed by the API or logged.
+type TLSCertificate struct {
+	EntryPoints []string `json:"entryPoints,omitempty"`
+	CertFile    string   `json:"certFile,omitempty"`
+	KeyFile     string   `json:"keyFile,omitempty"`
+}
+
+// TCPBackend holds a TCP backend configuration, whose servers are load
+// balanced with weighted round robin.
+type TCPBackend struct {
+	Servers              map[string]TCPServer `json:"servers,omitempty"`
+	DialTimeout          string               `json:"dialTimeout,omitempty"`
+	ProxyProtocolVersion int                  `json:"proxyProtocolVersion,omitempty"`
+}
+
+// TCPServer holds a TCP server configuration.
+type TCPServer struct {
+	Address string `json:"address,omitempty"`
+	Weight  int    `json:"weight"`
+}
+
+// TCPFrontend holds a TCP frontend configuration.
+// It matches the TLS connections requesting one of the SNI server names, or
+// the connections matching no other frontend of its entrypoints if SNI is
+// empty. TLS connections are terminated with the certificates of the
+// entrypoint, unless Passthrough is set.
+type TCPFrontend struct {
+	EntryPoints []string `json:"entryPoints,omitempty"`
+	Backend     string   `json:"backend,omitempty"`
+	SNI         []string `json:"sni,omitempty"`
+	Passthrough bool     `json:"passthrough,omitempty"`
+}
+
+// UDPBackend holds a UDP backend configuration, whose servers are load
+// balanced with weighted round robin.
+type UDPBackend struct {
+	Servers map[string]UDPServer `json:"servers,omitempty"`
+}
+
+// UDPServer holds a UDP server configuration.
+type UDPServer struct {
+	Address string `json:"address,omitempty"`
+	Weight  int    `json:"weight"`
+}
+
+// UDPFrontend holds a UDP frontend configuration.
+// The datagrams of a client address are sent to the same server of the
+// backend, until the session has no datagram during IdleTimeout (30s by
+// default).
+type UDPFrontend struct {
+	EntryPoints []string `json:"entryPoints,omitempty"`
+	Backend     string   `json:"backend,omitempty"`
+	IdleTimeout string   `json:"idleTimeout,omitempty"`
 }
 
 // ConfigMessage hold configuration information exchanged between parts of traefik.
@@ -183,3 +413,69 @@ func (cs *Constraints) SetValue(val interface{}) {
 func (cs *Constraints) Type() string {
 	return fmt.Sprint("constraint")
 }
+
+// ClientTLS holds TLS specific configurations as client
+// CA, Cert and Key can be either path or file contents.
+// It is the backend counterpart of provider.ClientTLS, which can't be used
+// here as the provider package imports this one.
+type ClientTLS struct {
+	CA                 string `json:"ca,omitempty" description:"TLS CA"`
+	Cert               string `json:"cert,omitempty" description:"TLS cert"`
+	Key                string `json:"key,omitempty" description:"TLS key"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty" description:"TLS insecure skip verify"`
+	ServerName         string `json:"serverName,omitempty" description:"TLS server name"`
+}
+
+// CreateTLSConfig creates a TLS config from ClientTLS structures
+func (clientTLS *ClientTLS) CreateTLSConfig() (*tls.Config, error) {
+	var err error
+	TLSConfig := &tls.Config{
+		InsecureSkipVerify: clientTLS.InsecureSkipVerify,
+		ServerName:         clientTLS.ServerName,
+	}
+	if clientTLS.CA != "" {
+		var ca []byte
+		if _, errCA := os.Stat(clientTLS.CA); errCA == nil {
+			ca, err = ioutil.ReadFile(clientTLS.CA)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read CA. %s", err)
+			}
+		} else {
+			ca = []byte(clientTLS.CA)
+		}
+		caPool := x509.NewCertPool()
+		caPool.AppendCertsFromPEM(ca)
+		TLSConfig.RootCAs = caPool
+	}
+
+	// a client certificate is optional, e.g. to only trust a custom CA
+	if clientTLS.Cert == "" && clientTLS.Key == "" {
+		return TLSConfig, nil
+	}
+
+	cert := tls.Certificate{}
+	_, errKeyIsFile := os.Stat(clientTLS.Key)
+
+	if _, errCertIsFile := os.Stat(clientTLS.Cert); errCertIsFile == nil {
+		if errKeyIsFile == nil {
+			cert, err = tls.LoadX509KeyPair(clientTLS.Cert, clientTLS.Key)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to load TLS keypair: %v", err)
+			}
+		} else {
+			return nil, fmt.Errorf("tls cert is a file, but tls key is not")
+		}
+	} else {
+		if errKeyIsFile != nil {
+			cert, err = tls.X509KeyPair([]byte(clientTLS.Cert), []byte(clientTLS.Key))
+			if err != nil {
+				return nil, fmt.Errorf("Failed to load TLS keypair: %v", err)
+
+			}
+		} else {
+			return nil, fmt.Errorf("tls key is a file, but tls cert is not")
+		}
+	}
+	TLSConfig.Certificates = []tls.Certificate{cert}
+	return TLSConfig, nil
+}
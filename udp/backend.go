@@ -0,0 +1,69 @@
+package udp
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// Backend load balances the sessions between UDP servers with smooth weighted
+// round robin.
+type Backend struct {
+	Name    string
+	lock    sync.Mutex
+	servers []*server
+}
+
+type server struct {
+	address       string
+	weight        int
+	currentWeight int
+}
+
+// NewBackend returns a new Backend without server.
+func NewBackend(name string) *Backend {
+	return &Backend{Name: name}
+}
+
+// AddServer adds a server to the backend. A weight lower than 1 is set to 1.
+func (b *Backend) AddServer(address string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.servers = append(b.servers, &server{address: address, weight: weight})
+}
+
+// Dial returns a connection to the next server of the backend.
+func (b *Backend) Dial() (*net.UDPConn, error) {
+	address, err := b.next()
+	if err != nil {
+		return nil, err
+	}
+	serverAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return net.DialUDP("udp", nil, serverAddr)
+}
+
+// next returns the address of the next server to send a session to.
+func (b *Backend) next() (string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.servers) == 0 {
+		return "", errors.New("No server in backend " + b.Name)
+	}
+	totalWeight := 0
+	var best *server
+	for _, server := range b.servers {
+		server.currentWeight += server.weight
+		totalWeight += server.weight
+		if best == nil || server.currentWeight > best.currentWeight {
+			best = server
+		}
+	}
+	best.currentWeight -= totalWeight
+	return best.address, nil
+}
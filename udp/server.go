@@ -0,0 +1,203 @@
+package udp
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/safe"
+)
+
+// DefaultIdleTimeout is the default duration after which a session without
+// datagram in either direction is closed.
+const DefaultIdleTimeout = 30 * time.Second
+
+// maxDatagramSize is the maximum size of a UDP datagram.
+const maxDatagramSize = 65535
+
+// Frontend sends the datagrams of each client to a server of its backend.
+type Frontend struct {
+	Name        string
+	Backend     *Backend
+	IdleTimeout time.Duration
+}
+
+// Server proxies the datagrams received on a UDP entrypoint to the backend of
+// its frontend. The datagrams of a client address form a session, sent to the
+// same server until it's idle. The frontend can be updated on configuration
+// reloads, the current sessions keeping their server until they are closed.
+type Server struct {
+	Addr     string
+	frontend safe.Safe
+	lock     sync.Mutex
+	conn     *net.UDPConn
+	sessions map[string]*session
+	closed   bool
+}
+
+type session struct {
+	clientAddr   *net.UDPAddr
+	serverConn   *net.UDPConn
+	idleTimeout  time.Duration
+	lock         sync.Mutex
+	lastActivity time.Time
+}
+
+// NewServer returns a new Server listening on addr, without frontend.
+func NewServer(addr string) *Server {
+	server := &Server{
+		Addr:     addr,
+		sessions: make(map[string]*session),
+	}
+	server.frontend.Set((*Frontend)(nil))
+	return server
+}
+
+// UpdateFrontend sets the frontend of the new sessions, or nil to drop them.
+func (s *Server) UpdateFrontend(frontend *Frontend) {
+	s.frontend.Set(frontend)
+}
+
+// ListenAndServe listens on the address of the server and serves the
+// datagrams until the server is closed.
+func (s *Server) ListenAndServe() error {
+	addr, err := net.ResolveUDPAddr("udp", s.Addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(conn)
+}
+
+// Serve serves the datagrams received on conn until the server is closed.
+func (s *Server) Serve(conn *net.UDPConn) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return conn.Close()
+	}
+	s.conn = conn
+	s.lock.Unlock()
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		n, clientAddr, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				log.Warnf("Error reading datagram on %s: %v", s.Addr, err)
+				continue
+			}
+			return err
+		}
+		session := s.getSession(clientAddr)
+		if session == nil {
+			continue
+		}
+		session.touch()
+		if _, err := session.serverConn.Write(buffer[:n]); err != nil {
+			log.Debugf("Error sending datagram from %s to %s: %v", clientAddr, session.serverConn.RemoteAddr(), err)
+		}
+	}
+}
+
+// Close stops listening and closes the current sessions.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	for _, session := range s.sessions {
+		session.serverConn.Close()
+	}
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+// getSession returns the session of a client, creating it with the current
+// frontend if needed. It returns nil if the datagram must be dropped.
+func (s *Server) getSession(clientAddr *net.UDPAddr) *session {
+	key := clientAddr.String()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if session, ok := s.sessions[key]; ok {
+		return session
+	}
+	frontend := s.frontend.Get().(*Frontend)
+	if frontend == nil {
+		log.Debugf("No UDP frontend on %s for datagram from %s", s.Addr, clientAddr)
+		return nil
+	}
+	serverConn, err := frontend.Backend.Dial()
+	if err != nil {
+		log.Errorf("Error connecting to backend %s of frontend %s: %v", frontend.Backend.Name, frontend.Name, err)
+		return nil
+	}
+	idleTimeout := frontend.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	session := &session{
+		clientAddr:   clientAddr,
+		serverConn:   serverConn,
+		idleTimeout:  idleTimeout,
+		lastActivity: time.Now(),
+	}
+	s.sessions[key] = session
+	log.Debugf("Creating UDP session from %s to %s", clientAddr, serverConn.RemoteAddr())
+	safe.Go(func() {
+		s.serveSession(session)
+		s.lock.Lock()
+		delete(s.sessions, key)
+		s.lock.Unlock()
+		session.serverConn.Close()
+	})
+	return session
+}
+
+// serveSession sends the datagrams of the server back to the client, until
+// the session is idle or closed.
+func (s *Server) serveSession(session *session) {
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		session.serverConn.SetReadDeadline(time.Now().Add(session.idleTimeout))
+		n, err := session.serverConn.Read(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() && !session.idle() {
+				continue
+			}
+			log.Debugf("Closing UDP session from %s to %s: %v", session.clientAddr, session.serverConn.RemoteAddr(), err)
+			return
+		}
+		session.touch()
+		if _, err := s.conn.WriteToUDP(buffer[:n], session.clientAddr); err != nil {
+			log.Debugf("Error sending datagram from %s to %s: %v", session.serverConn.RemoteAddr(), session.clientAddr, err)
+		}
+	}
+}
+
+// touch records a datagram of the session.
+func (session *session) touch() {
+	session.lock.Lock()
+	defer session.lock.Unlock()
+	session.lastActivity = time.Now()
+}
+
+// idle returns whether the session had no datagram during its idle timeout.
+func (session *session) idle() bool {
+	session.lock.Lock()
+	defer session.lock.Unlock()
+	return time.Since(session.lastActivity) >= session.idleTimeout
+}
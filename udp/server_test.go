@@ -0,0 +1,124 @@
+package udp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startEchoServer starts a UDP server answering each datagram with its name
+// and the datagram.
+func startEchoServer(t *testing.T, name string) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	go func() {
+		buffer := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+			conn.WriteToUDP([]byte(name+" "+string(buffer[:n])), addr)
+		}
+	}()
+	return conn
+}
+
+func startProxy(t *testing.T, frontend *Frontend) *Server {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	server := NewServer(conn.LocalAddr().String())
+	server.UpdateFrontend(frontend)
+	go server.Serve(conn)
+	return server
+}
+
+func exchange(t *testing.T, conn *net.UDPConn, message string) string {
+	if _, err := conn.Write([]byte(message)); err != nil {
+		t.Fatalf("Error writing: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buffer := make([]byte, maxDatagramSize)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		t.Fatalf("Error reading: %v", err)
+	}
+	return string(buffer[:n])
+}
+
+func dial(t *testing.T, address string) *net.UDPConn {
+	addr, _ := net.ResolveUDPAddr("udp", address)
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatalf("Error connecting to the proxy: %v", err)
+	}
+	return conn
+}
+
+func TestServerSessions(t *testing.T) {
+	server1 := startEchoServer(t, "server1")
+	defer server1.Close()
+	server2 := startEchoServer(t, "server2")
+	defer server2.Close()
+	backend := NewBackend("backend1")
+	backend.AddServer(server1.LocalAddr().String(), 1)
+	backend.AddServer(server2.LocalAddr().String(), 1)
+	proxy := startProxy(t, &Frontend{Name: "frontend1", Backend: backend})
+	defer proxy.Close()
+
+	client1 := dial(t, proxy.Addr)
+	defer client1.Close()
+	client2 := dial(t, proxy.Addr)
+	defer client2.Close()
+
+	first := exchange(t, client1, "ping")
+	if !strings.HasSuffix(first, " ping") {
+		t.Fatalf("Unexpected answer %q", first)
+	}
+	// the datagrams of a client are sent to the same server
+	if again := exchange(t, client1, "ping"); again != first {
+		t.Fatalf("Expected %q from the server of the session, got %q", first, again)
+	}
+	// a new client is sent to the next server
+	if other := exchange(t, client2, "ping"); other == first {
+		t.Fatalf("Expected the answer of the other server, got %q", other)
+	}
+
+	// sessions survive a reload
+	proxy.UpdateFrontend(&Frontend{Name: "frontend1", Backend: NewBackend("backend2")})
+	if again := exchange(t, client1, "ping"); again != first {
+		t.Fatalf("Expected %q from the server of the session after reload, got %q", first, again)
+	}
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	server1 := startEchoServer(t, "server1")
+	defer server1.Close()
+	backend := NewBackend("backend1")
+	backend.AddServer(server1.LocalAddr().String(), 1)
+	proxy := startProxy(t, &Frontend{Name: "frontend1", Backend: backend, IdleTimeout: 50 * time.Millisecond})
+	defer proxy.Close()
+
+	client := dial(t, proxy.Addr)
+	defer client.Close()
+	exchange(t, client, "ping")
+	proxy.lock.Lock()
+	sessions := len(proxy.sessions)
+	proxy.lock.Unlock()
+	if sessions != 1 {
+		t.Fatalf("Expected 1 session, got %d", sessions)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	proxy.lock.Lock()
+	sessions = len(proxy.sessions)
+	proxy.lock.Unlock()
+	if sessions != 0 {
+		t.Fatalf("Idle session should be closed, got %d sessions", sessions)
+	}
+}
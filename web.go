@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"runtime"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/containous/mux"
 	"github.com/containous/traefik/autogen"
+	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 	"github.com/elazarl/go-bindata-assetfs"
@@ -84,6 +86,7 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/backends/{backend}").HandlerFunc(provider.getBackendHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/backends/{backend}/servers").HandlerFunc(provider.getServersHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/backends/{backend}/servers/{server}").HandlerFunc(provider.getServerHandler)
+	systemRouter.Methods("GET").Path("/api/providers/{provider}/backends/{backend}/outliers").HandlerFunc(provider.getOutliersHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends").HandlerFunc(provider.getFrontendsHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}").HandlerFunc(provider.getFrontendHandler)
 	systemRouter.Methods("GET").Path("/api/providers/{provider}/frontends/{frontend}/routes").HandlerFunc(provider.getRoutesHandler)
@@ -122,7 +125,7 @@ func (provider *WebProvider) getHealthHandler(response http.ResponseWriter, requ
 
 func (provider *WebProvider) getConfigHandler(response http.ResponseWriter, request *http.Request) {
 	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
-	templatesRenderer.JSON(response, http.StatusOK, currentConfigurations)
+	templatesRenderer.JSON(response, http.StatusOK, redactConfigurations(currentConfigurations))
 }
 
 func (provider *WebProvider) getProviderHandler(response http.ResponseWriter, request *http.Request) {
@@ -130,7 +133,7 @@ func (provider *WebProvider) getProviderHandler(response http.ResponseWriter, re
 	providerID := vars["provider"]
 	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
 	if provider, ok := currentConfigurations[providerID]; ok {
-		templatesRenderer.JSON(response, http.StatusOK, provider)
+		templatesRenderer.JSON(response, http.StatusOK, redactConfiguration(provider))
 	} else {
 		http.NotFound(response, request)
 	}
@@ -192,6 +195,32 @@ func (provider *WebProvider) getServerHandler(response http.ResponseWriter, requ
 	http.NotFound(response, request)
 }
 
+// getOutliersHandler returns the ejection state of the servers of a backend
+// with outlier detection, indexed by server name.
+func (provider *WebProvider) getOutliersHandler(response http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+	providerID := vars["provider"]
+	backendID := vars["backend"]
+	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	outlierDetectors := provider.server.outlierDetectors.Get().(map[string]*middlewares.OutlierDetector)
+	if provider, ok := currentConfigurations[providerID]; ok {
+		if backend, ok := provider.Backends[backendID]; ok {
+			if outlierDetector, ok := outlierDetectors[backendID]; ok {
+				state := outlierDetector.State()
+				outliers := make(map[string]middlewares.OutlierServerState)
+				for serverName, server := range backend.Servers {
+					if url, err := url.Parse(server.URL); err == nil {
+						outliers[serverName] = state[url.String()]
+					}
+				}
+				templatesRenderer.JSON(response, http.StatusOK, outliers)
+				return
+			}
+		}
+	}
+	http.NotFound(response, request)
+}
+
 func (provider *WebProvider) getFrontendsHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]